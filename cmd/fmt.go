@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	tl "github.com/stefanovazzocell/ToyLanguage/src"
+)
+
+// Formats source files
+// Returns the process exit code
+func format(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "rewrite the files in place")
+	diff := flags.Bool("d", false, "show the changes as a diff")
+	width := flags.Int("width", tl.FormatWidth, "maximum line width")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		fmt.Println("Usage: toylanguage fmt [-w] [-d] [--width <int>] <file>...")
+		return ExitParseError
+	}
+	status := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("Failed to read %s: %v\n", path, err)
+			status = ExitParseError
+			continue
+		}
+		formatted := tl.Format(src, *width)
+		if *diff && !bytes.Equal(src, formatted) {
+			fmt.Printf("--- %s\n+++ %s (formatted)\n", path, path)
+			fmt.Print(tl.LineDiff(string(src), string(formatted)))
+		}
+		if *write {
+			if bytes.Equal(src, formatted) {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				fmt.Printf("Failed to write %s: %v\n", path, err)
+				status = ExitRuntimeError
+				continue
+			}
+			if err := os.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+				fmt.Printf("Failed to write %s: %v\n", path, err)
+				status = ExitRuntimeError
+			}
+		} else if !*diff {
+			os.Stdout.Write(formatted)
+		}
+	}
+	return status
+}
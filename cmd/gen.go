@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	tl "github.com/stefanovazzocell/ToyLanguage/src"
+)
+
+// Prints generated code
+// Returns the process exit code
+func gen(args []string) int {
+	if len(args) == 0 || args[0] != "text" {
+		fmt.Println("Usage: toylanguage gen text [--optimize size|speed] <text>")
+		return ExitParseError
+	}
+	flags := flag.NewFlagSet("gen text", flag.ExitOnError)
+	optimize := flags.String("optimize", "size", "optimize for the code size or the executed instructions (size or speed)")
+	newline := flags.Bool("n", false, "also print a newline after the text")
+	flags.Parse(args[1:])
+	goal, ok := tl.GenGoals[*optimize]
+	if !ok {
+		fmt.Printf("Invalid options: unknown goal %q\n", *optimize)
+		return ExitParseError
+	}
+	text := strings.Join(flags.Args(), " ")
+	if *newline {
+		text += "\n"
+	}
+	fmt.Printf("%s\n", tl.GenerateText([]byte(text), goal))
+	return 0
+}
@@ -1,32 +1,293 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	tl "github.com/stefanovazzocell/ToyLanguage/src"
 )
 
+// A flag that can be repeated
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// A repeatable flag adding files to a shared allowlist, keeping their order
+type fileFlag struct {
+	opts     *[]tl.ProgramOption
+	readOnly bool
+}
+
+func (f fileFlag) String() string {
+	return ""
+}
+
+func (f fileFlag) Set(value string) error {
+	if f.readOnly {
+		*f.opts = append(*f.opts, tl.WithReadOnlyFiles(value))
+	} else {
+		*f.opts = append(*f.opts, tl.WithFiles(value))
+	}
+	return nil
+}
+
+// Parses the options of a run command, extra defines the options specific to the command
+// Returns the program options, the source path and an error if the options are invalid
+func parseRunFlags(command string, args []string, extra ...func(*flag.FlagSet)) ([]tl.ProgramOption, string, error) {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	opts := []tl.ProgramOption{}
+	flags.Var(fileFlag{opts: &opts}, "file", "allow the program to read and write a file (can be repeated)")
+	flags.Var(fileFlag{opts: &opts, readOnly: true}, "file-ro", "allow the program to read a file (can be repeated)")
+	var netAllow listFlag
+	seed := flags.Int64("seed", 0, "seed for the random extension and the threads scheduler")
+	netHost := flags.String("net-host", "", "host the network extension connects to")
+	netBind := flags.String("net-bind", "", "host the network extension listens on")
+	netPort := flags.Int("net-port", tl.NetBasePort, "port for a data pointer byte of 0")
+	netIPv6 := flags.Bool("net-ipv6", false, "use IPv6 for the network extension")
+	netUnix := flags.String("net-unix", "", "use the Unix domain sockets in a directory instead of TCP")
+	flags.Var(&netAllow, "net-allow", "allow a host for --net-host and --net-bind (can be repeated)")
+	var netAllowPorts listFlag
+	flags.Var(&netAllowPorts, "net-allow-port", "only allow the given ports (can be repeated)")
+	netPolicy := flags.String("net-policy", "loopback", "network policy: loopback, allowlist or disabled")
+	netAudit := flags.String("net-audit", "", "append the network audit log to a file ('-' for stderr)")
+	netRecord := flags.String("net-record", "", "record the network traffic to a file")
+	netReplay := flags.String("net-replay", "", "play back the network traffic recorded in a file")
+	netTLS := flags.Bool("net-tls", false, "use TLS for the network connections")
+	netTLSCert := flags.String("net-tls-cert", "", "TLS certificate file for the listener")
+	netTLSKey := flags.String("net-tls-key", "", "TLS key file for the listener")
+	netTLSCA := flags.String("net-tls-ca", "", "TLS certificate authorities file trusted when connecting")
+	netTLSSelfSigned := flags.Bool("net-tls-self-signed", false, "use TLS with a self-signed certificate generated at startup")
+	for _, define := range extra {
+		define(flags)
+	}
+	flags.Parse(args)
+	if flags.NArg() < 1 {
+		fmt.Printf("Usage: toylanguage %s [OPTION] <file>\nTry 'toylanguage help' for more information.\n", command)
+		os.Exit(0)
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			opts = append(opts, tl.WithRandomSeed(*seed), tl.WithThreadSeed(*seed))
+		}
+	})
+	// Network
+	policy, ok := tl.NetPolicies[*netPolicy]
+	if !ok {
+		return nil, "", tl.ErrNetUnknownPolicy
+	}
+	netOpts := []tl.NetworkOption{tl.WithAllowedHosts(netAllow...), tl.WithBasePort(*netPort), tl.WithPolicy(policy)}
+	for _, port := range netAllowPorts {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid port %q", port)
+		}
+		netOpts = append(netOpts, tl.WithAllowedPorts(p))
+	}
+	if *netAudit == "-" {
+		// Keep stderr open when the network is closed
+		netOpts = append(netOpts, tl.WithAuditLog(struct{ io.Writer }{os.Stderr}))
+	} else if *netAudit != "" {
+		auditLog, err := os.OpenFile(*netAudit, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, "", err
+		}
+		netOpts = append(netOpts, tl.WithAuditLog(auditLog))
+	}
+	if *netHost != "" {
+		netOpts = append(netOpts, tl.WithTargetHost(*netHost))
+	}
+	if *netBind != "" {
+		netOpts = append(netOpts, tl.WithListenHost(*netBind))
+	}
+	if *netIPv6 {
+		netOpts = append(netOpts, tl.WithIPv6())
+	}
+	if *netUnix != "" {
+		netOpts = append(netOpts, tl.WithUnixSockets(*netUnix))
+	}
+	useTLS := *netTLSSelfSigned || *netTLS || *netTLSCert != "" || *netTLSKey != "" || *netTLSCA != ""
+	if useTLS && (*netRecord != "" || *netReplay != "") {
+		// The log would hold the encrypted traffic, which can't be played back
+		return nil, "", errors.New("--net-record and --net-replay can't be used with TLS")
+	}
+	if *netTLSSelfSigned {
+		config, err := tl.NewSelfSignedTLSConfig()
+		if err != nil {
+			return nil, "", err
+		}
+		netOpts = append(netOpts, tl.WithTLS(config))
+	} else if useTLS {
+		config, err := tl.NewTLSConfig(*netTLSCert, *netTLSKey, *netTLSCA)
+		if err != nil {
+			return nil, "", err
+		}
+		netOpts = append(netOpts, tl.WithTLS(config))
+	}
+	transport := tl.NewSystemTransport()
+	if *netReplay != "" {
+		file, err := os.Open(*netReplay)
+		if err != nil {
+			return nil, "", err
+		}
+		defer file.Close()
+		if transport, err = tl.NewReplayTransport(file); err != nil {
+			return nil, "", err
+		}
+	}
+	if *netRecord != "" {
+		file, err := os.Create(*netRecord)
+		if err != nil {
+			return nil, "", err
+		}
+		transport = tl.NewRecordingTransport(transport, file)
+	}
+	netOpts = append(netOpts, tl.WithTransport(transport))
+	network := tl.NewNetwork(netOpts...)
+	if err := network.Validate(); err != nil {
+		return nil, "", err
+	}
+	opts = append(opts, tl.WithNetwork(network))
+	return opts, flags.Arg(0), nil
+}
+
 // Load a given program
-func Load(programSrc string) (tl.Program, error) {
+func Load(programSrc string, opts ...tl.ProgramOption) (tl.Program, error) {
 	// Open source
 	file, err := os.Open(programSrc)
 	if err != nil {
-		fmt.Printf("Error opening %p: %v\n", &programSrc, err)
+		return tl.Program{}, err
 	}
 	defer file.Close()
 	// Parse
-	prog, err := tl.NewProgram(file)
+	prog, err := tl.NewProgram(file, opts...)
 	return prog, err
 }
 
+// Prints the enabled extensions
+func displayExtensions(program tl.Program) {
+	if program.HasExtensions(tl.ExtUdp) {
+		fmt.Print("Network Extension Enabled (datagram mode)\n\n")
+	} else if program.HasExtensions(tl.ExtNet) {
+		fmt.Print("Network Extension Enabled\n\n")
+	}
+	if program.HasExtensions(tl.ExtThr) {
+		fmt.Print("Threads Extension Enabled\n\n")
+	}
+	if program.HasExtensions(tl.ExtFio) {
+		fmt.Print("Files Extension Enabled\n\n")
+	}
+	if program.HasExtensions(tl.ExtTim) {
+		fmt.Print("Time Extension Enabled\n\n")
+	}
+	if program.HasExtensions(tl.ExtRnd) {
+		fmt.Print("Random Extension Enabled\n\n")
+	}
+	if program.HasExtensions(tl.ExtExt) {
+		fmt.Print("Exit Extension Enabled\n\n")
+	}
+}
+
 // Outputs a help guide in the screen and quits
 func displayHelp() {
 	fmt.Print(`ToyLanguage Help
 
-run <file>          - Run a program
-rununlimited <file> - Run a program with no execution limits 
-help                - Display this guide
+run [OPTION] <file>          - Run a program
+rununlimited [OPTION] <file> - Run a program with no execution limits 
+repl                         - Run instructions line by line, keeping the memory
+serve [OPTION]               - Start the playground: a web page and a JSON API to run
+                               programs, with the network extension disabled
+fmt [OPTION] <file>...       - Format source files, printing the result
+minify <file>                - Print the smallest source running like a program
+lint [-json] <file>...       - Report likely bugs as file:line:col: check: message
+                               (or a JSON array), exits with 1 if any is found
+gen text [OPTION] <text>     - Print code printing the text
+test [OPTION] <dir>          - Run the golden tests of a directory (foo.bf with foo.out),
+                               exits with 1 if any fails
+profile [OPTION] <file>      - Run a program, then report its hottest loops and
+                               instructions on stderr
+help                         - Display this guide
+
+Options:
+--file <path>           - Allow the program to read and write a file with the files
+                          extension, creating it if missing, the n-th --file or
+                          --file-ro has index n-1 (can be repeated)
+--file-ro <path>        - Allow the program to read a file with the files extension,
+                          it is never written or created (can be repeated)
+--seed <int>            - Seed the random extension and the threads scheduler
+                          to make runs reproducible
+--net-host <host>       - Host the network extension connects to (default 127.0.0.1)
+--net-bind <host>       - Host the network extension listens on (default 127.0.0.1,
+                          0.0.0.0 with the allowlist policy)
+--net-port <port>       - Port for a data pointer byte of 0 (default 42000)
+--net-ipv6              - Use IPv6, the default hosts become ::1 (and :: with the
+                          allowlist policy)
+--net-unix <dir>        - Use the Unix domain sockets <dir>/<port>.sock instead of TCP
+--net-allow <host>      - Allow a host for --net-host and --net-bind (can be repeated)
+--net-allow-port <port> - Only allow the given ports (can be repeated)
+--net-policy <policy>   - What the network extension can reach: loopback (loopback
+                          hosts only, also listening on the loopback host, default),
+                          allowlist (allowed hosts only, listening on every
+                          interface) or disabled
+--net-audit <path>      - Append every network operation to a log file ('-' for stderr)
+--net-tls               - Use TLS for the network connections, trusting the system
+                          certificate authorities
+--net-tls-cert <path>   - TLS certificate file for the listener (enables TLS)
+--net-tls-key <path>    - TLS key file for the listener (enables TLS)
+--net-tls-ca <path>     - Only trust the certificate authorities in a file when
+                          connecting (enables TLS)
+--net-tls-self-signed   - Use TLS with a self-signed certificate generated at startup,
+                          for local tests
+--net-record <path>     - Record the network traffic to a file (not with TLS)
+--net-replay <path>     - Play back the network traffic recorded with --net-record,
+                          without opening any sockets (not with TLS)
+
+Serve options:
+--addr <host:port>      - Address to listen on (default 127.0.0.1:8080)
+--max-steps <int>       - Instructions a run can execute, at least 1 (default 10000000)
+--max-time <duration>   - Wall time a run can take, loading included (default 5s)
+--max-output <bytes>    - Bytes a run can output (default 65536)
+--max-source <bytes>    - Bytes of a request (default 1048576)
+
+Fmt options:
+-w                      - Rewrite the files in place
+-d                      - Show the changes as a diff
+--width <int>           - Maximum line width (default 80)
+
+Gen options:
+--optimize <goal>       - Optimize for the code size (size, default) or for the
+                          executed instructions (speed)
+-n                      - Also print a newline after the text
+
+Test options:
+--parallel <int>        - Tests running at the same time (default: the number of CPUs)
+--timeout <duration>    - Wall time a test can take, a slower test fails (default 10s)
+--junit <path>          - Write a JUnit XML report to a file
+
+Profile options (and the run options):
+--top <int>             - Loops and instructions to report (default 10)
+--folded <path>         - Write the folded stacks for flame graph tools to a file
+--folded-weight <w>     - Weight of the folded stacks: executions (count, default)
+                          or nanoseconds (time)
+
+Exit status:
+0   - The program terminated
+2   - The program failed to load, or an option is invalid
+3   - The program terminated with an error, or a file given in the options
+      couldn't be opened
+4   - The program reached the execution limit
+Programs using the exit extension set their own exit status.
 `)
 	os.Exit(0)
 }
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tl "github.com/stefanovazzocell/ToyLanguage/src"
+)
+
+/*
+* Tests
+**/
+
+func TestParseRunFlagsRecordTLS(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "net.log")
+	for _, args := range [][]string{
+		{"--net-record", log, "--net-tls", "main.bf"},
+		{"--net-replay", log, "--net-tls-self-signed", "main.bf"},
+		{"--net-tls-ca", "ca.pem", "--net-record", log, "main.bf"},
+	} {
+		if _, _, err := parseRunFlags("run", args); err == nil {
+			t.Errorf("Expected recording or replaying with TLS to fail for %q", args)
+		}
+	}
+	opts, _, err := parseRunFlags("run", []string{"--net-record", log, "main.bf"})
+	if err != nil {
+		t.Fatalf("Expected recording without TLS to work, instead got %v", err)
+	}
+	// Close the log
+	program, err := tl.NewProgram(strings.NewReader(""), opts...)
+	if err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	program.Close()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	tl "github.com/stefanovazzocell/ToyLanguage/src"
+)
+
+// A problem found in a file
+type lintProblem struct {
+	File string `json:"file"`
+	tl.Diagnostic
+}
+
+// Reports likely bugs in source files, one per line as file:line:col: check: message
+// Returns the process exit code
+func lint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the problems as a JSON array")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		fmt.Println("Usage: toylanguage lint [-json] <file>...")
+		return ExitParseError
+	}
+	problems := []lintProblem{}
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", path, err)
+			return ExitParseError
+		}
+		for _, diagnostic := range tl.Lint(src) {
+			problems = append(problems, lintProblem{File: path, Diagnostic: diagnostic})
+		}
+	}
+	if *asJSON {
+		json.NewEncoder(os.Stdout).Encode(problems)
+	} else {
+		for _, p := range problems {
+			fmt.Printf("%s:%d:%d: %s: %s\n", p.File, p.Line, p.Col, p.Check, p.Message)
+		}
+	}
+	if len(problems) > 0 {
+		return ExitLintError
+	}
+	return 0
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"math"
 	"os"
 
@@ -12,6 +14,17 @@ const (
 	ExecutionLimit = 1000000000
 )
 
+// Process exit codes, programs using the exit extension choose their own
+const (
+	ExitParseError   = 2
+	ExitRuntimeError = 3
+	ExitLimitError   = 4
+	// Problems found by lint
+	ExitLintError = 1
+	// Tests failed
+	ExitTestError = 1
+)
+
 func main() {
 	if len(os.Args) < 2 {
 		fmt.Println("Usage: toylanguage COMMAND [OPTION]\nTry 'toylanguage help' for more information.")
@@ -19,53 +32,81 @@ func main() {
 	}
 	switch os.Args[1] {
 	case "run":
-		if len(os.Args) < 3 {
-			fmt.Println("Usage: toylanguage run <file>\nTry 'toylanguage help' for more information.")
-			os.Exit(0)
-		}
-		program, err := Load(os.Args[2])
-		if err != nil {
-			fmt.Printf("Failed to load program: %v\n", err)
-			return
-		}
-		if program.HasExtensions(tl.ExtNet) {
-			fmt.Print("Network Extension Enabled\n\n")
-		}
-		// Run
-		if err = program.Run(ExecutionLimit); err != nil {
-			fmt.Printf("\n\nProgram terminated with error: %v", err)
-		}
-		fmt.Println()
+		os.Exit(run("run", os.Args[2:], false))
 	case "rununlimited":
-		if len(os.Args) < 3 {
-			fmt.Println("Usage: toylanguage rununlimited <file>\nTry 'toylanguage help' for more information.")
-			os.Exit(0)
-		}
-		program, err := Load(os.Args[2])
-		if err != nil {
-			fmt.Printf("Failed to load program: %v\n", err)
-			return
-		}
-		if program.HasExtensions(tl.ExtNet) {
-			fmt.Print("Network Extension Enabled\n\n")
-		}
-		// Run
-		for {
-			err = program.Run(math.MaxInt)
-			if err == nil {
-				// Terminated
-				fmt.Println()
-				return
-			}
-			if err != tl.ErrExecutionLimit {
-				// Errored
-				fmt.Printf("\n\nProgram terminated with error: %v", err)
-				return
-			}
-		}
+		os.Exit(run("rununlimited", os.Args[2:], true))
+	case "repl":
+		os.Exit(repl())
+	case "serve":
+		os.Exit(serve(os.Args[2:]))
+	case "fmt":
+		os.Exit(format(os.Args[2:]))
+	case "minify":
+		os.Exit(minify(os.Args[2:]))
+	case "lint":
+		os.Exit(lint(os.Args[2:]))
+	case "gen":
+		os.Exit(gen(os.Args[2:]))
+	case "test":
+		os.Exit(test(os.Args[2:]))
+	case "profile":
+		os.Exit(profile(os.Args[2:]))
 	case "help":
 		displayHelp()
 	default:
 		fmt.Println("Usage: toylanguage COMMAND [OPTION]\nTry 'toylanguage help' for more information.")
 	}
 }
+
+// Loads and runs a program
+// Returns the process exit code
+func run(command string, args []string, unlimited bool) int {
+	opts, src, err := parseRunFlags(command, args)
+	if err != nil {
+		return optionsExitCode(err)
+	}
+	program, err := Load(src, opts...)
+	if err != nil {
+		fmt.Printf("Failed to load program: %v\n", err)
+		return ExitParseError
+	}
+	defer program.Close()
+	displayExtensions(program)
+	// Run
+	err = program.Run(ExecutionLimit)
+	for unlimited && err == tl.ErrExecutionLimit {
+		err = program.Run(math.MaxInt)
+	}
+	return exitCode(err)
+}
+
+// Reports options that can't be used
+// Returns the process exit code: ExitRuntimeError if a file couldn't be opened, ExitParseError otherwise
+func optionsExitCode(err error) int {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		fmt.Printf("Failed to open %s: %v\n", pathErr.Path, pathErr.Err)
+		return ExitRuntimeError
+	}
+	fmt.Printf("Invalid options: %v\n", err)
+	return ExitParseError
+}
+
+// Reports how a program terminated
+// Returns the process exit code
+func exitCode(err error) int {
+	var exitErr *tl.ExitError
+	if err == nil {
+		fmt.Println()
+		return 0
+	}
+	if errors.As(err, &exitErr) {
+		fmt.Println()
+		return int(exitErr.Code)
+	}
+	fmt.Printf("\n\nProgram terminated with error: %v\n", err)
+	if err == tl.ErrExecutionLimit {
+		return ExitLimitError
+	}
+	return ExitRuntimeError
+}
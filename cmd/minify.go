@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	tl "github.com/stefanovazzocell/ToyLanguage/src"
+)
+
+// Prints the smallest equivalent source of a file
+// Returns the process exit code
+func minify(args []string) int {
+	if len(args) != 1 {
+		fmt.Println("Usage: toylanguage minify <file>")
+		return ExitParseError
+	}
+	src, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Printf("Failed to read %s: %v\n", args[0], err)
+		return ExitParseError
+	}
+	fmt.Printf("%s\n", tl.Minify(src))
+	return 0
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	tl "github.com/stefanovazzocell/ToyLanguage/src"
+)
+
+// Runs a program and reports where it spends its time
+// Returns the process exit code
+func profile(args []string) int {
+	var top *int
+	var folded, foldedWeight *string
+	opts, src, err := parseRunFlags("profile", args, func(flags *flag.FlagSet) {
+		top = flags.Int("top", 10, "loops and instructions to report")
+		folded = flags.String("folded", "", "write the folded stacks for flame graphs to a file")
+		foldedWeight = flags.String("folded-weight", "count", "weight of the folded stacks: count or time")
+	})
+	if err == nil && *foldedWeight != "count" && *foldedWeight != "time" {
+		err = fmt.Errorf("unknown folded weight %q", *foldedWeight)
+	}
+	if err != nil {
+		return optionsExitCode(err)
+	}
+	source, err := os.ReadFile(src)
+	if err != nil {
+		fmt.Printf("Failed to load program: %v\n", err)
+		return ExitParseError
+	}
+	prof := tl.NewProfile()
+	program, err := Load(src, append(opts, tl.WithProfile(prof))...)
+	if err != nil {
+		fmt.Printf("Failed to load program: %v\n", err)
+		return ExitParseError
+	}
+	defer program.Close()
+	displayExtensions(program)
+	// Run
+	err = program.Run(ExecutionLimit)
+	prof.Stop()
+	status := exitCode(err)
+	// Report on stderr, keeping the program output apart
+	instructions := tl.InstructionTokens(source)
+	steps, total := prof.Total()
+	fmt.Fprintf(os.Stderr, "\nProfile: %d instructions in %s\n", steps, total.Round(time.Microsecond))
+	fmt.Fprintf(os.Stderr, "\nHottest loops:\n%-12s %12s %12s %12s %8s\n", "POSITION", "ITERATIONS", "ENTRIES", "STEPS", "TIME")
+	for i, loop := range prof.Loops(instructions) {
+		if i == *top {
+			break
+		}
+		fmt.Fprintf(os.Stderr, "%-12s %12d %12d %12d %7.2f%%\n", fmt.Sprintf("%d:%d", loop.Line, loop.Col),
+			loop.Iterations, loop.Entries, loop.Steps, share(loop.Time, total))
+	}
+	fmt.Fprintf(os.Stderr, "\nHottest instructions:\n%-12s %-11s %12s %8s\n", "POSITION", "INSTRUCTION", "COUNT", "TIME")
+	for i, instruction := range prof.Instructions(instructions) {
+		if i == *top {
+			break
+		}
+		token := instruction.Token
+		fmt.Fprintf(os.Stderr, "%-12s %-11s %12d %7.2f%%\n", fmt.Sprintf("%d:%d", token.Line, token.Col),
+			token.Text, instruction.Count, share(instruction.Time, total))
+	}
+	if *folded != "" {
+		file, err := os.Create(*folded)
+		if err == nil {
+			err = prof.WriteFolded(file, instructions, *foldedWeight == "time")
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			fmt.Printf("Failed to write the folded stacks: %v\n", err)
+			return ExitRuntimeError
+		}
+	}
+	return status
+}
+
+// Returns part as a percentage of total
+func share(part, total time.Duration) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(part) / float64(total)
+}
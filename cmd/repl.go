@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	tl "github.com/stefanovazzocell/ToyLanguage/src"
+)
+
+// Number of cells shown around the pointer after every line
+const replWindow = 8
+
+// Reads snippets line by line and runs them against a persistent memory
+// Returns the process exit code
+func repl() int {
+	program, err := tl.NewProgram(strings.NewReader(""))
+	if err != nil {
+		fmt.Printf("Failed to start: %v\n", err)
+		return ExitRuntimeError
+	}
+	defer program.Close()
+	// Lines and the program input share stdin
+	input := bufio.NewReader(os.Stdin)
+	program.IOReader = input
+	fmt.Println("ToyLanguage REPL, type :help for the commands")
+	for {
+		fmt.Print("tl> ")
+		line, err := input.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Println()
+			return 0
+		}
+		line = strings.TrimSpace(line)
+		if line == ":quit" {
+			return 0
+		}
+		if strings.HasPrefix(line, ":") {
+			replCommand(&program, line)
+			continue
+		}
+		program.LoadSnippet(strings.NewReader(line))
+		replRun(&program)
+	}
+}
+
+// Runs a REPL command
+func replCommand(program *tl.Program, line string) {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case ":reset":
+		program.Reset()
+		showTape(program)
+	case ":mem":
+		fmt.Printf("pointer: %d\nmemory: %d\n", program.Memory.Pointer(), program.Memory.Bytes())
+	case ":load":
+		if len(fields) != 2 {
+			fmt.Println("Usage: :load <file>")
+			return
+		}
+		file, err := os.Open(fields[1])
+		if err != nil {
+			fmt.Printf("Failed to load program: %v\n", err)
+			return
+		}
+		defer file.Close()
+		if err := program.LoadSnippet(file); err != nil {
+			fmt.Printf("Failed to load program: %v\n", err)
+			return
+		}
+		replRun(program)
+	case ":ext":
+		for _, name := range fields[1:] {
+			ext, ok := tl.SupportedExtensions[name]
+			if !ok {
+				fmt.Printf("Unknown extension %q\n", name)
+				return
+			}
+			program.EnableExtensions(ext)
+		}
+		fmt.Printf("Enabled extensions: %s\n", strings.Join(enabledExtensions(program), " "))
+	case ":help":
+		fmt.Print(`Type instructions to run them, the memory is kept between lines
+:reset        - Reset the memory
+:mem          - Show the pointer and the memory up to the last non-zero byte
+:load <file>  - Run a program from a file, keeping the memory
+:ext [code]   - Enable extensions (ex: :ext net thr) or list the enabled ones
+:quit         - Exit
+`)
+	default:
+		fmt.Printf("Unknown command %q, type :help for the commands\n", fields[0])
+	}
+}
+
+// Runs the loaded snippet and shows the tape
+func replRun(program *tl.Program) {
+	err := program.Run(ExecutionLimit)
+	var exitErr *tl.ExitError
+	if errors.As(err, &exitErr) {
+		fmt.Printf("\nExit status %d\n", exitErr.Code)
+	} else if err != nil {
+		fmt.Printf("\nError: %v\n", err)
+	}
+	showTape(program)
+}
+
+// Returns the codes of the enabled extensions
+func enabledExtensions(program *tl.Program) []string {
+	names := []string{}
+	for name, ext := range tl.SupportedExtensions {
+		if program.HasExtensions(ext) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Prints the cells around the pointer, the pointer cell is between brackets
+func showTape(program *tl.Program) {
+	pointer := program.Memory.Pointer()
+	from := pointer - replWindow
+	if from < 0 {
+		from = 0
+	}
+	to := from + 2*replWindow
+	if to > tl.MemSize-1 {
+		to = tl.MemSize - 1
+		from = to - 2*replWindow
+	}
+	fmt.Printf("\n%5d:", from)
+	for i := from; i <= to; i++ {
+		if i == pointer {
+			fmt.Printf(" [%3d]", program.Memory.Peek(i))
+		} else {
+			fmt.Printf("  %3d ", program.Memory.Peek(i))
+		}
+	}
+	fmt.Println()
+}
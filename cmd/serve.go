@@ -0,0 +1,259 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tl "github.com/stefanovazzocell/ToyLanguage/src"
+)
+
+// Default limits of a playground run
+const (
+	ServeMaxSteps  = 10000000
+	ServeMaxTime   = 5 * time.Second
+	ServeMaxOutput = 64 * 1024
+	ServeMaxSource = 1024 * 1024
+)
+
+// How long a run can take to stop after the time limit, before it's abandoned
+const serveStopGrace = 100 * time.Millisecond
+
+var (
+	errServeTimeLimit   = errors.New("reached the time limit")
+	errServeOutputLimit = errors.New("reached the output limit")
+	errServeThreads     = errors.New("the threads extension is not available in the playground")
+)
+
+//go:embed playground.html
+var playgroundPage []byte
+
+// The limits of every playground run
+type serveLimits struct {
+	steps  int
+	time   time.Duration
+	output int
+	source int64
+}
+
+// A playground run request
+type runRequest struct {
+	Source string `json:"source"`
+	Input  string `json:"input"`
+}
+
+// The result of a playground run
+type runResponse struct {
+	Output   string `json:"output"`
+	Error    string `json:"error,omitempty"`
+	ExitCode int    `json:"exitCode"`
+	Steps    int    `json:"steps"`
+	Memory   []int  `json:"memory"`
+}
+
+// A writer failing once the limit is reached
+type limitedWriter struct {
+	mu      sync.Mutex
+	builder strings.Builder
+	limit   int
+}
+
+func (w *limitedWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.builder.Len()+len(b) > w.limit {
+		return 0, errServeOutputLimit
+	}
+	return w.builder.Write(b)
+}
+
+// Returns the output written so far
+func (w *limitedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.builder.String()
+}
+
+// A program that failed to load
+type loadError struct {
+	err error
+}
+
+func (e loadError) Error() string {
+	return fmt.Sprintf("failed to load program: %v", e.err)
+}
+
+// Returns an error if a limit would not bound the runs
+func (l serveLimits) validate() error {
+	if l.steps < 1 {
+		return fmt.Errorf("--max-steps must be at least 1, got %d", l.steps)
+	}
+	if l.time <= 0 {
+		return fmt.Errorf("--max-time must be positive, got %s", l.time)
+	}
+	return nil
+}
+
+// Starts the playground server
+// Returns the process exit code
+func serve(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "127.0.0.1:8080", "address to listen on")
+	limits := serveLimits{}
+	flags.IntVar(&limits.steps, "max-steps", ServeMaxSteps, "instructions a run can execute")
+	flags.DurationVar(&limits.time, "max-time", ServeMaxTime, "wall time a run can take")
+	flags.IntVar(&limits.output, "max-output", ServeMaxOutput, "bytes a run can output")
+	flags.Int64Var(&limits.source, "max-source", ServeMaxSource, "bytes of a request")
+	flags.Parse(args)
+	if err := limits.validate(); err != nil {
+		fmt.Printf("Invalid limits: %v\n", err)
+		return ExitParseError
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(playgroundPage)
+	})
+	mux.HandleFunc("/api/run", func(w http.ResponseWriter, r *http.Request) {
+		handleRun(w, r, limits)
+	})
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Printf("Serving the playground on http://%s\n", *addr)
+	if err := server.ListenAndServe(); err != nil {
+		fmt.Printf("Failed to serve: %v\n", err)
+		return ExitRuntimeError
+	}
+	return 0
+}
+
+// Runs the program of a request and replies with the result
+func handleRun(w http.ResponseWriter, r *http.Request, limits serveLimits) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req runRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, limits.source)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runSandboxed(req, limits))
+}
+
+// Runs a program within the limits, without network, files or threads
+func runSandboxed(req runRequest, limits serveLimits) runResponse {
+	output := &limitedWriter{limit: limits.output}
+	return runLimited(func() (*tl.Program, error) {
+		program, err := tl.NewProgram(strings.NewReader(req.Source),
+			tl.WithNetwork(tl.NewNetwork(tl.WithPolicy(tl.NetPolicyDisabled))),
+			// Sleeping must not hold the server
+			tl.WithClock(&tl.FakeClock{}))
+		if err != nil {
+			return nil, err
+		}
+		if program.HasExtensions(tl.ExtThr) {
+			// Every thread copies the memory
+			program.Close()
+			return nil, errServeThreads
+		}
+		program.IOReader = strings.NewReader(req.Input)
+		program.IOWriter = output
+		return &program, nil
+	}, output, limits)
+}
+
+// Loads a program with load and runs it within the limits, then closes it, output is its writer
+// The time limit includes the loading, a program still blocked after it is abandoned without reporting its memory
+func runLimited(load func() (*tl.Program, error), output *limitedWriter, limits serveLimits) runResponse {
+	res := runResponse{Memory: []int{}}
+	var loaded atomic.Pointer[tl.Program]
+	var stop atomic.Bool
+	var steps atomic.Int64
+	done := make(chan error, 1)
+	go func() {
+		program, err := load()
+		if err != nil {
+			done <- loadError{err}
+			return
+		}
+		defer program.Close()
+		loaded.Store(program)
+		for err == nil {
+			if steps.Load() == int64(limits.steps) {
+				err = tl.ErrExecutionLimit
+			} else if stop.Load() {
+				err = errServeTimeLimit
+			} else if err = program.RunNext(); err == nil {
+				steps.Add(1)
+			}
+		}
+		done <- err
+	}()
+	timer := time.NewTimer(limits.time)
+	defer timer.Stop()
+	var err error
+	select {
+	case err = <-done:
+	case <-timer.C:
+		// Stop between instructions, closing the network ends its waits
+		stop.Store(true)
+		if program := loaded.Load(); program != nil {
+			program.Network.Close()
+		}
+		select {
+		case err = <-done:
+			if !errors.As(err, new(loadError)) {
+				err = errServeTimeLimit
+			}
+		case <-time.After(serveStopGrace):
+			res.Error = errServeTimeLimit.Error()
+			res.ExitCode = ExitLimitError
+			res.Steps = int(steps.Load())
+			res.Output = output.String()
+			return res
+		}
+	}
+	res.Steps = int(steps.Load())
+	// Report
+	var exitErr *tl.ExitError
+	switch {
+	case errors.As(err, new(loadError)):
+		res.Error = err.Error()
+		res.ExitCode = ExitParseError
+		return res
+	case err == tl.ErrProgramDone:
+	case errors.As(err, &exitErr):
+		res.ExitCode = int(exitErr.Code)
+	case err == tl.ErrIoNoOutput && len(output.String()) >= limits.output:
+		res.Error = errServeOutputLimit.Error()
+		res.ExitCode = ExitLimitError
+	case err == tl.ErrExecutionLimit || err == errServeTimeLimit:
+		res.Error = err.Error()
+		res.ExitCode = ExitLimitError
+	default:
+		res.Error = err.Error()
+		res.ExitCode = ExitRuntimeError
+	}
+	res.Output = output.String()
+	for _, b := range loaded.Load().Memory.Bytes() {
+		res.Memory = append(res.Memory, int(b))
+	}
+	return res
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	tl "github.com/stefanovazzocell/ToyLanguage/src"
+)
+
+/*
+* Tests
+**/
+
+func TestRunSandboxed(t *testing.T) {
+	limits := serveLimits{steps: 1000, time: time.Second, output: 4, source: ServeMaxSource}
+	testCases := []struct {
+		src      string
+		output   string
+		err      string
+		exitCode int
+		steps    int
+	}{
+		{"++.", "\x02", "", 0, 3},
+		{"+[]", "", tl.ErrExecutionLimit.Error(), ExitLimitError, 1000},
+		{"+[.]", "\x01\x01\x01\x01", errServeOutputLimit.Error(), ExitLimitError, 10},
+		{"tl:net ?", "", "", 0, 1},
+		{"tl:thr +[Y+]", "", loadError{errServeThreads}.Error(), ExitParseError, 0},
+	}
+	for _, test := range testCases {
+		res := runSandboxed(runRequest{Source: test.src}, limits)
+		if res.Output != test.output || res.Error != test.err || res.ExitCode != test.exitCode || res.Steps != test.steps {
+			t.Errorf("Expected %q (error %q, exit code %d) in %d steps from %q, instead got %q (error %q, exit code %d) in %d steps",
+				test.output, test.err, test.exitCode, test.steps, test.src, res.Output, res.Error, res.ExitCode, res.Steps)
+		}
+	}
+}
+
+func TestRunSandboxedTimeLimit(t *testing.T) {
+	limits := serveLimits{steps: ServeMaxSteps * 100, time: 50 * time.Millisecond, output: ServeMaxOutput}
+	// A busy program stops between instructions and reports its memory
+	res := runSandboxed(runRequest{Source: "+[]"}, limits)
+	if res.Error != errServeTimeLimit.Error() || res.ExitCode != ExitLimitError || len(res.Memory) == 0 {
+		t.Fatalf("Expected the time limit with the memory, instead got %q (exit code %d) and memory %v", res.Error, res.ExitCode, res.Memory)
+	}
+	// A blocked program is abandoned
+	input, blocked := io.Pipe()
+	defer blocked.Close()
+	output := &limitedWriter{limit: ServeMaxOutput}
+	start := time.Now()
+	res = runLimited(func() (*tl.Program, error) {
+		program, err := tl.NewProgram(strings.NewReader(".,"))
+		program.IOReader = input
+		program.IOWriter = output
+		return &program, err
+	}, output, limits)
+	if elapsed := time.Since(start); elapsed > limits.time+time.Second {
+		t.Fatalf("Expected to abandon the blocked program, instead took %s", elapsed)
+	}
+	if res.Error != errServeTimeLimit.Error() || res.ExitCode != ExitLimitError || res.Output != "\x00" || res.Steps != 1 || len(res.Memory) != 0 {
+		t.Fatalf("Expected the time limit after 1 step without the memory, instead got %q %q (exit code %d) in %d steps and memory %v",
+			res.Output, res.Error, res.ExitCode, res.Steps, res.Memory)
+	}
+}
+
+func TestRunSandboxedLoadTime(t *testing.T) {
+	limits := serveLimits{steps: ServeMaxSteps, time: 50 * time.Millisecond, output: ServeMaxOutput}
+	// The time limit starts before loading the program
+	loading := make(chan bool)
+	defer close(loading)
+	start := time.Now()
+	res := runLimited(func() (*tl.Program, error) {
+		<-loading
+		return nil, io.EOF
+	}, &limitedWriter{limit: ServeMaxOutput}, limits)
+	if elapsed := time.Since(start); elapsed > limits.time+time.Second {
+		t.Fatalf("Expected to abandon the slow load, instead took %s", elapsed)
+	}
+	if res.Error != errServeTimeLimit.Error() || res.ExitCode != ExitLimitError || res.Steps != 0 {
+		t.Fatalf("Expected the time limit while loading, instead got %q (exit code %d) in %d steps", res.Error, res.ExitCode, res.Steps)
+	}
+}
+
+func TestServeLimitsValidate(t *testing.T) {
+	testCases := []struct {
+		limits serveLimits
+		valid  bool
+	}{
+		{serveLimits{steps: ServeMaxSteps, time: ServeMaxTime}, true},
+		{serveLimits{steps: 1, time: time.Millisecond}, true},
+		{serveLimits{steps: 0, time: ServeMaxTime}, false},
+		{serveLimits{steps: -1, time: ServeMaxTime}, false},
+		{serveLimits{steps: ServeMaxSteps, time: 0}, false},
+	}
+	for _, test := range testCases {
+		if err := test.limits.validate(); (err == nil) != test.valid {
+			t.Errorf("Expected the limits %+v to be valid: %v, instead got %v", test.limits, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	tl "github.com/stefanovazzocell/ToyLanguage/src"
+)
+
+// Runs the golden tests of a directory
+// Returns the process exit code
+func test(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	parallel := flags.Int("parallel", runtime.NumCPU(), "tests running at the same time")
+	junit := flags.String("junit", "", "write a JUnit XML report to a file")
+	timeout := flags.Duration("timeout", tl.GoldenTimeout, "wall time a test can take")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Println("Usage: toylanguage test [--parallel <int>] [--timeout <duration>] [--junit <path>] <dir>")
+		return ExitParseError
+	}
+	dir := flags.Arg(0)
+	tests, err := tl.FindGoldenTests(dir)
+	if err != nil {
+		fmt.Printf("Failed to find the tests: %v\n", err)
+		return ExitParseError
+	}
+	for i := range tests {
+		tests[i].Timeout = *timeout
+	}
+	results := tl.RunGoldenTests(tests, *parallel)
+	failed := 0
+	for _, result := range results {
+		if result.Passed() {
+			fmt.Printf("PASS %s (%s)\n", result.Test.Name, result.Duration.Round(time.Microsecond))
+			continue
+		}
+		failed++
+		fmt.Printf("FAIL %s (%s)\n", result.Test.Name, result.Duration.Round(time.Microsecond))
+		fmt.Printf("    %s\n", strings.ReplaceAll(strings.TrimRight(result.Failure, "\n"), "\n", "\n    "))
+	}
+	fmt.Printf("%d passed, %d failed\n", len(results)-failed, failed)
+	if *junit != "" {
+		file, err := os.Create(*junit)
+		if err == nil {
+			err = tl.WriteJUnit(file, dir, results)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			fmt.Printf("Failed to write the JUnit report: %v\n", err)
+			return ExitRuntimeError
+		}
+	}
+	if failed > 0 {
+		return ExitTestError
+	}
+	return 0
+}
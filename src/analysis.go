@@ -0,0 +1,374 @@
+package interpreter
+
+import "bytes"
+
+const (
+	// Loop iterations joined before the pointer range and the written cells are widened
+	analysisWidening = 2
+	// Loop iterations before giving up on the state of a loop
+	analysisIterations = 64
+	// Instructions interpreted and cells copied or joined before giving up on the analysis,
+	// nested loops are interpreted again on every iteration of the loops around them
+	analysisBudget = 1 << 17
+)
+
+// Cells written starting at the data pointer by the instructions setting more than one
+var analysisWrites = map[byte]int{
+	'|':  2,
+	'(':  2,
+	'/':  3,
+	'`':  18, // An IPv6 address and a port
+	'\'': TimBytes,
+	'Y':  2,
+}
+
+// Extension instructions leaving the memory as it is
+var analysisReadOnly = map[byte]bool{
+	'*': true, '@': true, '^': true, '\\': true, 'W': true, '%': true, '"': true, '!': true,
+}
+
+// The results of the static analysis of a program starting with a blank memory
+type Analysis struct {
+	// Lowest and highest position of the pointer before each instruction
+	Low  []int
+	High []int
+	// Instructions that can run
+	Reachable []bool
+	// Value of the current cell before each instruction, -1 if it's not constant
+	Cell []int
+	// Loops moving the pointer back where they started, by index of the opening '['
+	Balanced []bool
+	// Highest cell the pointer can reach
+	MaxCell int
+	// The brackets are balanced and the analysis finished within its budget,
+	// the other results are only set if true
+	Complete bool
+	// Work left before giving up on the analysis (see analysisBudget)
+	budget int
+}
+
+// The abstract state of the program
+type absState struct {
+	reachable bool
+	// Range of the pointer
+	low, high int
+	// Cells with a known value, all cells outside [dirtyLow, dirtyHigh] are 0
+	cells               map[int]byte
+	dirtyLow, dirtyHigh int
+	// Other threads may change any cell at any time
+	shared bool
+}
+
+// Runs an abstract interpretation of instructions starting with a blank memory
+// and the pointer at 0, finding the pointer range and the constant cells
+// Returns the analysis
+func Analyze(instructions []byte) *Analysis {
+	if !balanced(instructions) {
+		return incompleteAnalysis(len(instructions))
+	}
+	a := &Analysis{
+		Low:       make([]int, len(instructions)),
+		High:      make([]int, len(instructions)),
+		Reachable: make([]bool, len(instructions)),
+		Cell:      make([]int, len(instructions)),
+		Balanced:  make([]bool, len(instructions)),
+		Complete:  true,
+		budget:    analysisBudget,
+	}
+	start := absState{reachable: true, cells: map[int]byte{}, dirtyLow: 0, dirtyHigh: -1,
+		shared: bytes.IndexByte(instructions, 'Y') >= 0}
+	end := a.block(instructions, 0, len(instructions), start)
+	if a.budget < 0 {
+		// Too expensive to analyze
+		return incompleteAnalysis(len(instructions))
+	}
+	a.MaxCell = 0
+	if end.reachable {
+		a.MaxCell = end.high
+	}
+	for i, reachable := range a.Reachable {
+		if reachable && a.High[i] > a.MaxCell {
+			a.MaxCell = a.High[i]
+		}
+	}
+	return a
+}
+
+// Returns the analysis of a program that couldn't be analyzed: every
+// instruction can run with the pointer anywhere and any cell value
+func incompleteAnalysis(length int) *Analysis {
+	a := &Analysis{
+		Low:       make([]int, length),
+		High:      make([]int, length),
+		Reachable: make([]bool, length),
+		Cell:      make([]int, length),
+		Balanced:  make([]bool, length),
+		MaxCell:   MemSize - 1,
+	}
+	for i := range a.Cell {
+		a.High[i] = MemSize - 1
+		a.Reachable[i] = true
+		a.Cell[i] = -1
+	}
+	return a
+}
+
+// Returns true if all the pointer positions are below the last cell
+func (a *Analysis) Bounded() bool {
+	return a.Complete && a.MaxCell < MemSize-1
+}
+
+// Returns for each instruction true if it's a move proven to keep the pointer in the memory
+func (a *Analysis) SafeMoves(instructions []byte) []bool {
+	safe := make([]bool, len(instructions))
+	if !a.Complete {
+		return safe
+	}
+	for i, b := range instructions {
+		safe[i] = a.Reachable[i] && ((b == '>' && a.High[i] < MemSize-1) || (b == '<' && a.Low[i] > 0))
+	}
+	return safe
+}
+
+// Interprets the instructions from start to end (excluded) and records the states
+// Returns the state after the last instruction
+func (a *Analysis) block(instructions []byte, start, end int, s absState) absState {
+	// The cells are changed in place
+	s = s.clone()
+	a.budget -= len(s.cells)
+	for i := start; i < end; i++ {
+		if a.budget--; a.budget < 0 {
+			// Out of budget, the results are discarded
+			return absState{}
+		}
+		a.record(i, s)
+		if !s.reachable {
+			continue
+		}
+		switch b := instructions[i]; b {
+		case '>':
+			if s.high == MemSize-1 {
+				s.high--
+			}
+			s.low++
+			s.high++
+			s.reachable = s.low <= s.high
+		case '<':
+			if s.low == 0 {
+				s.low++
+			}
+			s.low--
+			s.high--
+			s.reachable = s.low <= s.high
+		case '+', '-':
+			if v, ok := s.cell(); ok {
+				s.write(s.low, s.high, v+byte(44-int(b)), true) // '+' is 43, '-' is 45
+			} else {
+				s.write(s.low, s.high, 0, false)
+			}
+		case '[':
+			closing := matchingBracket(instructions, i)
+			a.Balanced[i] = loopBalanced(instructions[i+1 : closing])
+			s = a.loop(instructions, i, closing, s)
+			i = closing
+		default:
+			if b == '.' || analysisReadOnly[b] {
+				break
+			}
+			// Reads and extensions set any value
+			written := analysisWrites[b]
+			if written == 0 {
+				written = 1
+			}
+			s.write(s.low, s.high+written-1, 0, false)
+			if b == 'Y' && s.high < MemSize-1 {
+				// The forked thread runs the next instructions from the next cell
+				s.high++
+			}
+		}
+	}
+	return s
+}
+
+// Interprets the loop between the brackets at start and closing until its state is stable
+// Returns the state after the loop
+func (a *Analysis) loop(instructions []byte, start, closing int, entry absState) absState {
+	if v, ok := entry.cell(); ok && v == 0 {
+		// The loop can't be entered
+		a.block(instructions, start+1, closing+1, absState{})
+		return entry
+	}
+	head := entry
+	for iteration := 0; ; iteration++ {
+		end := a.block(instructions, start+1, closing, head)
+		a.record(closing, end)
+		a.budget -= len(head.cells) + len(end.cells)
+		next := join(head, end)
+		if iteration >= analysisWidening {
+			next = widen(head, next)
+		}
+		if next.equal(head) {
+			break
+		}
+		if iteration == analysisIterations {
+			// Nothing is known but the cells can't be changed by the instructions before
+			next = absState{reachable: true, low: 0, high: MemSize - 1, cells: map[int]byte{},
+				dirtyLow: 0, dirtyHigh: MemSize - 1, shared: entry.shared}
+		}
+		head = next
+	}
+	if v, ok := head.cell(); ok && v != 0 {
+		// The loop never ends
+		return absState{}
+	}
+	// The loop ends when the current cell is 0
+	exit := head.clone()
+	if exit.low == exit.high {
+		exit.cells[exit.low] = 0
+	}
+	return exit
+}
+
+// Records the state before instruction i
+func (a *Analysis) record(i int, s absState) {
+	a.Reachable[i] = s.reachable
+	a.Low[i], a.High[i] = s.low, s.high
+	a.Cell[i] = -1
+	if v, ok := s.cell(); ok && s.reachable {
+		a.Cell[i] = int(v)
+	}
+}
+
+// Returns the value of the current cell and true if it's constant
+func (s absState) cell() (byte, bool) {
+	if !s.reachable || s.shared || s.low != s.high {
+		return 0, false
+	}
+	if v, ok := s.cells[s.low]; ok {
+		return v, true
+	}
+	return 0, s.low < s.dirtyLow || s.low > s.dirtyHigh
+}
+
+// Sets the cells from low to high to v if known, to an unknown value otherwise
+func (s *absState) write(low, high int, v byte, known bool) {
+	if high-low < len(s.cells) {
+		for cell := low; cell <= high; cell++ {
+			delete(s.cells, cell)
+		}
+	} else {
+		for cell := range s.cells {
+			if cell >= low && cell <= high {
+				delete(s.cells, cell)
+			}
+		}
+	}
+	if known && low == high {
+		s.cells[low] = v
+	}
+	if s.dirtyLow > s.dirtyHigh {
+		s.dirtyLow, s.dirtyHigh = low, high
+	}
+	if low < s.dirtyLow {
+		s.dirtyLow = low
+	}
+	if high > s.dirtyHigh {
+		s.dirtyHigh = high
+	}
+}
+
+// Returns a copy of the state
+func (s absState) clone() absState {
+	cells := make(map[int]byte, len(s.cells))
+	for cell, value := range s.cells {
+		cells[cell] = value
+	}
+	s.cells = cells
+	return s
+}
+
+// Returns true if the states are the same
+func (s absState) equal(o absState) bool {
+	if s.reachable != o.reachable {
+		return false
+	}
+	if !s.reachable {
+		return true
+	}
+	if s.low != o.low || s.high != o.high || s.dirtyLow != o.dirtyLow || s.dirtyHigh != o.dirtyHigh ||
+		len(s.cells) != len(o.cells) {
+		return false
+	}
+	for cell, value := range s.cells {
+		if v, ok := o.cells[cell]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a state covering both states
+func join(s, o absState) absState {
+	if !s.reachable {
+		return o.clone()
+	}
+	if !o.reachable {
+		return s.clone()
+	}
+	j := absState{reachable: true, low: s.low, high: s.high, cells: map[int]byte{}, shared: s.shared || o.shared}
+	if o.low < j.low {
+		j.low = o.low
+	}
+	if o.high > j.high {
+		j.high = o.high
+	}
+	// A cell is known if both states agree on its value
+	known := func(st absState, cell int) (byte, bool) {
+		if v, ok := st.cells[cell]; ok {
+			return v, true
+		}
+		return 0, cell < st.dirtyLow || cell > st.dirtyHigh
+	}
+	for _, st := range []absState{s, o} {
+		for cell := range st.cells {
+			v1, ok1 := known(s, cell)
+			v2, ok2 := known(o, cell)
+			if ok1 && ok2 && v1 == v2 {
+				j.cells[cell] = v1
+			}
+		}
+	}
+	j.dirtyLow, j.dirtyHigh = s.dirtyLow, s.dirtyHigh
+	if o.dirtyLow <= o.dirtyHigh {
+		if j.dirtyLow > j.dirtyHigh {
+			j.dirtyLow, j.dirtyHigh = o.dirtyLow, o.dirtyHigh
+		}
+		if o.dirtyLow < j.dirtyLow {
+			j.dirtyLow = o.dirtyLow
+		}
+		if o.dirtyHigh > j.dirtyHigh {
+			j.dirtyHigh = o.dirtyHigh
+		}
+	}
+	return j
+}
+
+// Returns next with the ranges growing since prev extended to the memory boundary
+func widen(prev, next absState) absState {
+	if !prev.reachable || !next.reachable {
+		return next
+	}
+	if next.low < prev.low {
+		next.low = 0
+	}
+	if next.high > prev.high {
+		next.high = MemSize - 1
+	}
+	if prev.dirtyLow <= prev.dirtyHigh && next.dirtyLow < prev.dirtyLow {
+		next.dirtyLow = 0
+	}
+	if prev.dirtyLow <= prev.dirtyHigh && next.dirtyHigh > prev.dirtyHigh {
+		next.dirtyHigh = MemSize - 1
+	}
+	return next
+}
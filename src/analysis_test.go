@@ -0,0 +1,207 @@
+package interpreter
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+* Tests
+**/
+
+func TestAnalyze(t *testing.T) {
+	t.Run("PointerRange", func(t *testing.T) {
+		code := []byte(">>+[<]>")
+		a := Analyze(code)
+		if !a.Complete || !a.Bounded() || a.MaxCell != 3 {
+			t.Fatalf("Expected a bounded analysis up to cell 3, got %v %v %d", a.Complete, a.Bounded(), a.MaxCell)
+		}
+		expected := [][2]int{{0, 0}, {1, 1}, {2, 2}, {2, 2}, {0, 2}, {0, 1}, {0, 2}}
+		for i, r := range expected {
+			if a.Low[i] != r[0] || a.High[i] != r[1] || !a.Reachable[i] {
+				t.Errorf("Expected the pointer in %v before instruction %d, got [%d, %d]", r, i, a.Low[i], a.High[i])
+			}
+		}
+		if a := Analyze([]byte("+[>+]")); a.Bounded() || a.High[4] != MemSize-1 {
+			t.Fatalf("Expected an unbounded pointer, got up to %d", a.High[4])
+		}
+	})
+	t.Run("Constants", func(t *testing.T) {
+		code := []byte("++>-<[-]>.[+]")
+		a := Analyze(code)
+		expected := []int{0, 1, 2, 0, 255, 2, -1, -1, 0, 255, 255, -1, -1}
+		for i, v := range expected {
+			if a.Cell[i] != v {
+				t.Errorf("Expected cell %d before instruction %d, got %d", v, i, a.Cell[i])
+			}
+		}
+		if a := Analyze([]byte("[+]+[]>")); a.Reachable[1] || !a.Reachable[3] || a.Reachable[6] {
+			t.Fatalf("Expected the dead loop and the code after the infinite loop to be unreachable: %v", a.Reachable)
+		}
+		if a := Analyze([]byte("Y+[-]")); a.Cell[1] != -1 || a.Cell[4] != -1 {
+			t.Fatalf("Expected no constant cells with threads, got %v", a.Cell)
+		}
+		if a := Analyze([]byte("+>'<")); a.Cell[3] != -1 {
+			t.Fatalf("Expected the time extension to overwrite the previous cell, got %d", a.Cell[3])
+		}
+	})
+	t.Run("Balanced", func(t *testing.T) {
+		a := Analyze([]byte("+[>+<-]>[>]"))
+		if !a.Balanced[1] || a.Balanced[8] {
+			t.Fatalf("Expected only the first loop to be balanced, got %v", a.Balanced)
+		}
+		if a.Low[7] != 0 || a.High[7] != 0 {
+			t.Fatalf("Expected the pointer back at 0 after a balanced loop, got [%d, %d]", a.Low[7], a.High[7])
+		}
+	})
+	t.Run("SafeMoves", func(t *testing.T) {
+		code := []byte(">>+[<]<<")
+		safe := Analyze(code).SafeMoves(code)
+		expected := []bool{true, true, false, false, false, false, false, false}
+		for i := range expected {
+			if safe[i] != expected[i] {
+				t.Errorf("Expected safe move %v for instruction %d", expected[i], i)
+			}
+		}
+	})
+	t.Run("Unbalanced", func(t *testing.T) {
+		code := []byte("+]>")
+		a := Analyze(code)
+		if a.Complete || a.Bounded() || a.SafeMoves(code)[2] {
+			t.Fatal("Expected no results for unbalanced instructions")
+		}
+	})
+}
+
+func TestAnalyzeSamples(t *testing.T) {
+	samples, err := filepath.Glob("../samples/*.bf")
+	if err != nil || len(samples) == 0 {
+		t.Fatalf("Failed to find the samples: %v", err)
+	}
+	for _, sample := range samples {
+		src, err := os.ReadFile(sample)
+		if err != nil {
+			t.Fatalf("Failed to read sample: %v", err)
+		}
+		p, err := NewProgram(strings.NewReader(string(src)))
+		if err != nil {
+			t.Fatalf("Failed to load program: %v", err)
+		}
+		if p.HasExtensions(ExtNet) {
+			// The network samples wait for each other
+			continue
+		}
+		p.IOReader = strings.NewReader("Hello")
+		p.IOWriter = &strings.Builder{}
+		a := Analyze(p.GetInstructions())
+		// Every state of the run must be covered by the analysis
+		for steps := 0; steps < 100000; steps++ {
+			i := p.Instructions.pc
+			if i == len(p.GetInstructions()) {
+				break
+			}
+			pointer := p.Memory.Pointer()
+			if !a.Reachable[i] || pointer < a.Low[i] || pointer > a.High[i] || (a.Cell[i] >= 0 && int(p.Memory.Get()) != a.Cell[i]) {
+				t.Fatalf("Instruction %d of %s ran with the pointer at %d and the cell %d, analyzed [%d, %d] %d",
+					i, sample, pointer, p.Memory.Get(), a.Low[i], a.High[i], a.Cell[i])
+			}
+			if p.RunNext() != nil {
+				break
+			}
+		}
+		p.Close()
+	}
+}
+
+func TestSizedMemory(t *testing.T) {
+	p, err := NewProgram(strings.NewReader("+>+<"))
+	if err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	if len(p.Memory.mem) != MemChunk {
+		t.Fatalf("Expected a memory of %d cells, got %d", MemChunk, len(p.Memory.mem))
+	}
+	if err := p.LoadProgram(strings.NewReader("+[>+]")); err != nil || len(p.Memory.mem) != MemSize {
+		t.Fatalf("Expected the memory to grow when loading a program: %v", err)
+	}
+	for _, src := range []string{"+[>+]", "tl:thr Y"} {
+		p, err = NewProgram(strings.NewReader(src))
+		if err != nil {
+			t.Fatalf("Failed to load program: %v", err)
+		}
+		if len(p.Memory.mem) != MemSize {
+			t.Fatalf("Expected a full memory for %q, got %d cells", src, len(p.Memory.mem))
+		}
+	}
+}
+
+func TestAnalyzeDeepNesting(t *testing.T) {
+	for _, depth := range []int{4, 20, 200} {
+		code := []byte("+" + strings.Repeat("[>+", depth) + strings.Repeat("<-]", depth))
+		start := time.Now()
+		a := Analyze(code)
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Fatalf("Expected to analyze a nesting of %d loops in bounded time, instead took %s", depth, elapsed)
+		}
+		if a.Complete != (depth == 4) {
+			t.Fatalf("Expected the analysis of a nesting of %d loops to be complete only within budget, got %v", depth, a.Complete)
+		}
+		if !a.Complete && (a.MaxCell != MemSize-1 || !a.Reachable[len(code)-1] || a.High[1] != MemSize-1 || a.Cell[0] != -1) {
+			t.Fatalf("Expected an incomplete analysis to assume the full memory, got %+v", a)
+		}
+		p, err := NewProgram(bytes.NewReader(code))
+		if err != nil {
+			t.Fatalf("Failed to load program: %v", err)
+		}
+		if !a.Complete && len(p.Memory.mem) != MemSize {
+			t.Fatalf("Expected a full memory for a nesting of %d loops, got %d cells", depth, len(p.Memory.mem))
+		}
+		p.Close()
+	}
+}
+
+func TestAnalyzeRandomPrograms(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 500; n++ {
+		// A random program with balanced brackets
+		code := []byte{}
+		depth := 0
+		for i := 0; i < 30; i++ {
+			b := "+-<>>[]."[rng.Intn(8)]
+			if b == ']' && depth == 0 {
+				continue
+			}
+			if b == '[' {
+				depth++
+			} else if b == ']' {
+				depth--
+			}
+			code = append(code, b)
+		}
+		for ; depth > 0; depth-- {
+			code = append(code, ']')
+		}
+		p, err := NewProgram(bytes.NewReader(code))
+		if err != nil {
+			t.Fatalf("Failed to load program: %v", err)
+		}
+		p.IOWriter = io.Discard
+		a := Analyze(code)
+		for steps := 0; steps < 2000 && p.Instructions.pc < len(code); steps++ {
+			i, pointer := p.Instructions.pc, p.Memory.Pointer()
+			if !a.Reachable[i] || pointer < a.Low[i] || pointer > a.High[i] || (a.Cell[i] >= 0 && int(p.Memory.Get()) != a.Cell[i]) {
+				t.Fatalf("Instruction %d of %q ran with the pointer at %d and the cell %d, analyzed [%d, %d] %d",
+					i, code, pointer, p.Memory.Get(), a.Low[i], a.High[i], a.Cell[i])
+			}
+			if p.RunNext() != nil {
+				break
+			}
+		}
+	}
+}
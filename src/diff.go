@@ -0,0 +1,167 @@
+package interpreter
+
+import "strings"
+
+// Lines compared by LineDiff after the common start and end of the texts, the
+// lines after them are reported as removed and added without being compared
+const diffMaxLines = 4000
+
+// Compares two texts line by line
+// Returns the lines of a and b prefixed by " " if kept, "-" if removed or "+" if added
+func LineDiff(a, b string) string {
+	x := strings.SplitAfter(a, "\n")
+	y := strings.SplitAfter(b, "\n")
+	d := newLineDiffer(x, y)
+	// Common start and end
+	start, endX, endY := 0, len(x), len(y)
+	for start < endX && start < endY && d.x[start] == d.y[start] {
+		d.keptX[start], d.keptY[start] = true, true
+		start++
+	}
+	for endX > start && endY > start && d.x[endX-1] == d.y[endY-1] {
+		endX--
+		endY--
+		d.keptX[endX], d.keptY[endY] = true, true
+	}
+	if endX-start > diffMaxLines {
+		endX = start + diffMaxLines
+	}
+	if endY-start > diffMaxLines {
+		endY = start + diffMaxLines
+	}
+	d.compare(start, endX, start, endY)
+	var out strings.Builder
+	line := func(prefix, text string) {
+		if text == "" {
+			return
+		}
+		out.WriteString(prefix + strings.TrimSuffix(text, "\n") + "\n")
+	}
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && !d.keptX[i]:
+			line("-", x[i])
+			i++
+		case j < len(y) && !d.keptY[j]:
+			line("+", y[j])
+			j++
+		default:
+			line(" ", x[i])
+			i++
+			j++
+		}
+	}
+	return out.String()
+}
+
+// Finds the lines kept between two texts with the linear space algorithm of
+// Myers ("An O(ND) Difference Algorithm and Its Variations")
+type lineDiffer struct {
+	// The lines of the texts, equal lines have the same number
+	x, y []int
+	// Lines in the longest common subsequence
+	keptX, keptY []bool
+	// Furthest positions on the diagonals, forward and backward
+	forward, backward []int
+}
+
+// Returns a differ for the given lines
+func newLineDiffer(x, y []string) *lineDiffer {
+	d := &lineDiffer{
+		x:        make([]int, len(x)),
+		y:        make([]int, len(y)),
+		keptX:    make([]bool, len(x)),
+		keptY:    make([]bool, len(y)),
+		forward:  make([]int, len(x)+len(y)+3),
+		backward: make([]int, len(x)+len(y)+3),
+	}
+	ids := map[string]int{}
+	for i, lines := range [][]string{x, y} {
+		numbers := d.x
+		if i == 1 {
+			numbers = d.y
+		}
+		for j, text := range lines {
+			id, ok := ids[text]
+			if !ok {
+				id = len(ids)
+				ids[text] = id
+			}
+			numbers[j] = id
+		}
+	}
+	return d
+}
+
+// Marks the lines kept between x[x0:x1] and y[y0:y1]
+func (d *lineDiffer) compare(x0, x1, y0, y1 int) {
+	for x0 < x1 && y0 < y1 && d.x[x0] == d.y[y0] {
+		d.keptX[x0], d.keptY[y0] = true, true
+		x0++
+		y0++
+	}
+	for x0 < x1 && y0 < y1 && d.x[x1-1] == d.y[y1-1] {
+		x1--
+		y1--
+		d.keptX[x1], d.keptY[y1] = true, true
+	}
+	if x0 == x1 || y0 == y1 {
+		// Only removed or added lines
+		return
+	}
+	// Without a common start and end there are at least two differences,
+	// both sides of the middle snake have fewer
+	snakeX, snakeY, snakeEnd := d.middleSnake(x0, x1, y0, y1)
+	for i := snakeX; i < snakeEnd; i++ {
+		d.keptX[i], d.keptY[snakeY+i-snakeX] = true, true
+	}
+	d.compare(x0, snakeX, y0, snakeY)
+	d.compare(snakeEnd, x1, snakeY+snakeEnd-snakeX, y1)
+}
+
+// Finds the middle snake, the equal lines in the middle of a shortest edit
+// Returns the start of the snake in x and y, and its end in x
+func (d *lineDiffer) middleSnake(x0, x1, y0, y1 int) (int, int, int) {
+	n, m := x1-x0, y1-y0
+	delta := n - m
+	max := (n + m + 1) / 2
+	offset := max + 1
+	forward, backward := d.forward[:2*max+3], d.backward[:2*max+3]
+	forward[offset+1], backward[offset+1] = 0, 0
+	for steps := 0; steps <= max; steps++ {
+		// Forward on the diagonals k = x - y
+		for k := -steps; k <= steps; k += 2 {
+			x := forward[offset+k-1] + 1
+			if k == -steps || (k != steps && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			}
+			start := x
+			for x < n && x-k < m && d.x[x0+x] == d.y[y0+x-k] {
+				x++
+			}
+			forward[offset+k] = x
+			// The backward diagonal of the same position
+			if back := delta - k; delta%2 != 0 && back >= -(steps-1) && back <= steps-1 && x+backward[offset+back] >= n {
+				return x0 + start, y0 + start - k, x0 + x
+			}
+		}
+		// Backward from the end, on the diagonals k = (n - x) - (m - y)
+		for k := -steps; k <= steps; k += 2 {
+			x := backward[offset+k-1] + 1
+			if k == -steps || (k != steps && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			}
+			start := x
+			for x < n && x-k < m && d.x[x1-x-1] == d.y[y1-x+k-1] {
+				x++
+			}
+			backward[offset+k] = x
+			if front := delta - k; delta%2 == 0 && front >= -steps && front <= steps && x+forward[offset+front] >= n {
+				return x1 - x, y1 - x + k, x1 - start
+			}
+		}
+	}
+	// Unreachable, the texts differ in at most n + m lines
+	return x0, y0, x0
+}
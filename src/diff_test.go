@@ -0,0 +1,53 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+* Tests
+**/
+
+func TestLineDiff(t *testing.T) {
+	testCases := []struct {
+		a, b, diff string
+	}{
+		{"", "", ""},
+		{"a\nb\n", "a\nb\n", " a\n b\n"},
+		{"a\nb\nc\n", "a\nc\n", " a\n-b\n c\n"},
+		{"a\nc\n", "a\nb\nc\n", " a\n+b\n c\n"},
+		{"a\nb\n", "c\nd\n", "-a\n-b\n+c\n+d\n"},
+		{"a\nb\nc\na\nb\nb\na\n", "c\nb\na\nb\na\nc\n", "-a\n+c\n b\n-c\n a\n b\n-b\n a\n+c\n"},
+		{"a\nb", "a\nc", " a\n-b\n+c\n"},
+	}
+	for _, test := range testCases {
+		if diff := LineDiff(test.a, test.b); diff != test.diff {
+			t.Errorf("Expected the diff of %q and %q to be %q, instead got %q", test.a, test.b, test.diff, diff)
+		}
+	}
+}
+
+func TestLineDiffLarge(t *testing.T) {
+	// Large texts without any common line are compared in bounded time and memory
+	var a, b strings.Builder
+	for i := 0; i < 100000; i++ {
+		fmt.Fprintf(&a, "a%d\n", i)
+		fmt.Fprintf(&b, "b%d\n", i)
+	}
+	start := time.Now()
+	diff := LineDiff(a.String(), b.String())
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected to compare large texts in bounded time, instead took %s", elapsed)
+	}
+	if lines := strings.Count(diff, "\n"); lines != 200000 || !strings.HasPrefix(diff, "-a0\n") || !strings.HasSuffix(diff, "+b99999\n") {
+		t.Fatalf("Expected every line to be removed and added, instead got %d lines", lines)
+	}
+	// Lines changed in the middle of large texts
+	middle := strings.Replace(a.String(), "a50000\n", "changed\n", 1)
+	if diff := LineDiff(a.String(), middle); strings.Count(diff, "\n-") != 1 || !strings.Contains(diff, "\n-a50000\n+changed\n") {
+		t.Fatalf("Expected a single changed line, instead got %d removed lines", strings.Count(diff, "\n-"))
+	}
+}
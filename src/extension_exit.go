@@ -0,0 +1,19 @@
+package interpreter
+
+import "fmt"
+
+// Returned when the program ends itself with an exit code
+type ExitError struct {
+	Code byte
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("the program exited with code %d", e.Code)
+}
+
+// Terminates the program and all its threads with the given exit code
+func (p *Program) exit(code byte) error {
+	p.Instructions.pc = len(p.Instructions.instruction)
+	p.Threads.stop()
+	return &ExitError{Code: code}
+}
@@ -0,0 +1,57 @@
+package interpreter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+/*
+* Tests
+**/
+
+func TestExit(t *testing.T) {
+	t.Run("ExitCode", func(t *testing.T) {
+		p, err := NewProgram(strings.NewReader(`tl:ext +++!+++`))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		err = p.Run(100)
+		var exitErr *ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("Expected an ExitError, instead got %v", err)
+		}
+		if exitErr.Code != 3 {
+			t.Fatalf("Expected exit code 3, instead got %d", exitErr.Code)
+		}
+		if err = p.RunNext(); err != ErrProgramDone {
+			t.Fatalf("Expected ErrProgramDone after exiting, instead got %v", err)
+		}
+		if p.Memory.Get() != 3 {
+			t.Fatalf("Expected the program to stop at exit, instead the memory is %d", p.Memory.Get())
+		}
+	})
+	t.Run("Threads", func(t *testing.T) {
+		p, err := NewProgram(strings.NewReader(`tl:thr:ext Y[+++++!]+[]`))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		err = p.Run(100)
+		var exitErr *ExitError
+		if !errors.As(err, &exitErr) || exitErr.Code != 6 {
+			t.Fatalf("Expected an ExitError with code 6, instead got %v", err)
+		}
+		if err = p.RunNext(); err != ErrProgramDone {
+			t.Fatalf("Expected all threads to terminate, instead got %v", err)
+		}
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		p, err := NewProgram(strings.NewReader(`+!+`))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		if err = p.Run(100); err != nil {
+			t.Fatalf("Expected no error on Run, instead got %v", err)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package interpreter
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+var (
+	ErrFileNoHandle = errors.New("no file handle is selected")
+)
+
+// The file extension, gives access to an allowlist of files
+type Files struct {
+	// Paths the program is allowed to open, by index
+	allowlist []string
+	// Paths that can only be read, by index
+	readOnly []bool
+	// Open handles, by index (nil if not open)
+	handles []io.ReadWriteCloser
+	// Index of the selected handle (-1 if none)
+	active int
+}
+
+// Opens (if needed) and selects the file at the given allowlist index
+// Returns true if successful, false otherwise
+func (f *Files) Select(b byte) bool {
+	index := int(b)
+	if index >= len(f.allowlist) {
+		f.active = -1
+		return false
+	}
+	if f.handles[index] == nil {
+		flag := os.O_RDWR | os.O_CREATE
+		if f.readOnly[index] {
+			flag = os.O_RDONLY
+		}
+		file, err := os.OpenFile(f.allowlist[index], flag, 0644)
+		if err != nil {
+			f.active = -1
+			return false
+		}
+		f.handles[index] = file
+	}
+	f.active = index
+	return true
+}
+
+// Reads a byte from the selected file
+// Returns io.EOF at the end of the file
+func (f *Files) Read() (byte, error) {
+	if f.active == -1 {
+		return 0, ErrFileNoHandle
+	}
+	b := make([]byte, 1)
+	n, err := f.handles[f.active].Read(b)
+	if n == 0 && err == io.EOF {
+		return 0, io.EOF
+	}
+	if n != 1 || err != nil {
+		return 0, ErrIoNoInput
+	}
+	return b[0], nil
+}
+
+// Writes a byte to the selected file
+func (f *Files) Write(b byte) error {
+	if f.active == -1 {
+		return ErrFileNoHandle
+	}
+	n, err := f.handles[f.active].Write([]byte{b})
+	if n != 1 || err != nil {
+		return ErrIoNoOutput
+	}
+	return nil
+}
+
+// Closes all the open files and deselects the handle
+// Returns the first error encountered, if any
+func (f *Files) Close() error {
+	var firstErr error
+	for i, handle := range f.handles {
+		if handle == nil {
+			continue
+		}
+		if err := handle.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		f.handles[i] = nil
+	}
+	f.active = -1
+	return firstErr
+}
+
+// Adds paths to the allowlist
+func (f *Files) allow(readOnly bool, paths ...string) {
+	for _, path := range paths {
+		f.allowlist = append(f.allowlist, path)
+		f.readOnly = append(f.readOnly, readOnly)
+		f.handles = append(f.handles, nil)
+	}
+}
+
+// Allows the program to read and write the given paths, creating them if missing
+// The index of a path is its position among all the allowed paths
+func WithFiles(paths ...string) ProgramOption {
+	return func(p *Program) {
+		p.Files.allow(false, paths...)
+	}
+}
+
+// Allows the program to read the given paths, they are never written or created
+// The index of a path is its position among all the allowed paths
+func WithReadOnlyFiles(paths ...string) ProgramOption {
+	return func(p *Program) {
+		p.Files.allow(true, paths...)
+	}
+}
+
+// Returns the file extension with the given allowlist, the files can be read and written
+func NewFiles(paths ...string) *Files {
+	f := &Files{active: -1}
+	f.allow(false, paths...)
+	return f
+}
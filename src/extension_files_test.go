@@ -0,0 +1,158 @@
+package interpreter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/*
+* Tests
+**/
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	output := filepath.Join(dir, "output.txt")
+	if err := os.WriteFile(input, []byte("Hi"), 0644); err != nil {
+		t.Fatalf("Failed to setup input file: %v", err)
+	}
+
+	t.Run("Copy", func(t *testing.T) {
+		// Select input, read it all into memory, select output, write it back
+		p, err := NewProgram(strings.NewReader(`tl:fio $>(>(<< +$ >)>)`), WithFiles(input, output))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		defer p.Close()
+		t.Logf("Parsed %s", string(p.Instructions.instruction))
+		if err = p.Run(100); err != nil {
+			t.Fatalf("Expected no error on Run, instead got %v", err)
+		}
+		if p.Memory.mem[0] != 0 {
+			t.Fatalf("Expected a successful status for the output file, instead got %d", p.Memory.mem[0])
+		}
+		if err = p.Close(); err != nil {
+			t.Fatalf("Failed to close files: %v", err)
+		}
+		actual, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		if string(actual) != "Hi" {
+			t.Fatalf("Expected %q in output file, instead got %q", "Hi", actual)
+		}
+	})
+	t.Run("EndOfFile", func(t *testing.T) {
+		p, err := NewProgram(strings.NewReader(`tl:fio $>+++(`), WithFiles(filepath.Join(dir, "empty.txt")))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		defer p.Close()
+		if err = p.Run(100); err != nil {
+			t.Fatalf("Expected no error on Run, instead got %v", err)
+		}
+		if p.Memory.Get() != 0 || p.Memory.mem[2] != 2 {
+			t.Fatalf("Expected 0 and the end of file status, instead got %d and %d", p.Memory.Get(), p.Memory.mem[2])
+		}
+	})
+	t.Run("NulByte", func(t *testing.T) {
+		// A NUL byte is read successfully, the end of the file follows it
+		nul := filepath.Join(dir, "nul.txt")
+		if err := os.WriteFile(nul, []byte{0}, 0644); err != nil {
+			t.Fatalf("Failed to setup nul file: %v", err)
+		}
+		p, err := NewProgram(strings.NewReader(`tl:fio $>+(>>+(`), WithReadOnlyFiles(nul))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		defer p.Close()
+		if err = p.Run(100); err != nil {
+			t.Fatalf("Expected no error on Run, instead got %v", err)
+		}
+		if status := p.Memory.mem[:5]; status[1] != 0 || status[2] != 0 || status[3] != 0 || status[4] != 2 {
+			t.Fatalf("Expected the NUL byte then the end of the file, instead got %d", status)
+		}
+	})
+	t.Run("NotAllowed", func(t *testing.T) {
+		p, err := NewProgram(strings.NewReader(`tl:fio +$`), WithFiles(input))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		defer p.Close()
+		if err = p.Run(100); err != nil {
+			t.Fatalf("Expected no error on Run, instead got %v", err)
+		}
+		if p.Memory.Get() != 1 {
+			t.Fatalf("Expected a failed status for a file out of the allowlist, instead got %d", p.Memory.Get())
+		}
+	})
+	t.Run("NoHandle", func(t *testing.T) {
+		// Reading sets the next byte to 1, writing sets the current byte to 1
+		for code, status := range map[string]int{`tl:fio (`: 1, `tl:fio )`: 0} {
+			p, err := NewProgram(strings.NewReader(code))
+			if err != nil {
+				t.Fatalf("Failed to load test program: %v", err)
+			}
+			if err = p.Run(100); err != nil {
+				t.Fatalf("Expected no error on Run for %q, instead got %v", code, err)
+			}
+			if p.Memory.mem[status] != 1 {
+				t.Fatalf("Expected a failed status for %q, instead got %v", code, p.Memory.mem[:2])
+			}
+		}
+	})
+	t.Run("ReadOnly", func(t *testing.T) {
+		missing := filepath.Join(dir, "missing.txt")
+		// Read input, then fail to write it back, then fail to open a missing file
+		p, err := NewProgram(strings.NewReader(`tl:fio $>(>>)>+$`), WithReadOnlyFiles(input, missing))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		defer p.Close()
+		if err = p.Run(100); err != nil {
+			t.Fatalf("Expected no error on Run, instead got %v", err)
+		}
+		expected := []byte{0, 'H', 0, 1, 1}
+		if string(p.Memory.mem[:5]) != string(expected) {
+			t.Fatalf("Expected the memory %v, instead got %v", expected, p.Memory.mem[:5])
+		}
+		if _, err := os.Stat(missing); err == nil {
+			t.Fatalf("Expected the read-only file not to be created")
+		}
+		actual, err := os.ReadFile(input)
+		if err != nil || string(actual) != "Hi" {
+			t.Fatalf("Expected the read-only file to be unchanged, instead got %q (%v)", actual, err)
+		}
+	})
+	t.Run("Indexes", func(t *testing.T) {
+		p, err := NewProgram(strings.NewReader(`tl:fio +$`), WithReadOnlyFiles(input), WithFiles(output))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		defer p.Close()
+		if err = p.Run(100); err != nil {
+			t.Fatalf("Expected no error on Run, instead got %v", err)
+		}
+		if p.Memory.Get() != 0 || p.Files.readOnly[p.Files.active] {
+			t.Fatalf("Expected the second option to allow file 1 for writing")
+		}
+	})
+	t.Run("Reset", func(t *testing.T) {
+		p, err := NewProgram(strings.NewReader(`tl:fio $>(`), WithFiles(input))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		defer p.Close()
+		for i := 0; i < 2; i++ {
+			if err = p.Run(100); err != nil {
+				t.Fatalf("Expected no error on Run, instead got %v", err)
+			}
+			if p.Memory.Get() != 'H' {
+				t.Fatalf("Expected to read from the start of the file, instead got %c", p.Memory.Get())
+			}
+			p.Reset()
+		}
+	})
+}
@@ -1,22 +1,50 @@
 package interpreter
 
 import (
+	"crypto/tls"
 	"errors"
+	"io"
 	"net"
-	"runtime"
+	"os"
 	"strconv"
-	"sync/atomic"
+	"sync"
 	"time"
 )
 
+var (
+	ErrNetHostNotAllowed = errors.New("the network host is not allowed")
+	ErrNetInvalidPort    = errors.New("the network base port must be between 1 and 65280")
+)
+
 const (
-	// The target address
-	NetTargetAddr = "127.0.0.1:"
-	// The listening address
-	NetListenAddr = "0.0.0.0:"
+	// The default target host
+	NetTargetHost = "127.0.0.1"
+	// The default listening host
+	NetListenHost = "0.0.0.0"
+	// The default IPv6 target host
+	NetTargetHostIPv6 = "::1"
+	// The default IPv6 listening host
+	NetListenHostIPv6 = "::"
+	// The default port for a data pointer byte of 0
+	NetBasePort = 42000
+	// The default target address, followed by the port
+	//
+	// Deprecated: Use NetTargetHost, or WithTargetHost to change it.
+	NetTargetAddr = NetTargetHost + ":"
+	// The old listening address on all the interfaces, followed by the port, kept only as
+	// a deprecated alias: with the default loopback policy the network listens on the target host
+	//
+	// Deprecated: Use NetListenHost, or WithListenHost to change it.
+	NetListenAddr = NetListenHost + ":"
 	// Long timeout used for timeout = 0
 	// If the timeout is set to this all ops must retry until success
 	NetLongTimeout = time.Minute
+	// The maximum number of open connections
+	NetMaxConns = 256
+	// Chunks of received bytes a connection buffers before waiting for the program to read them
+	NetReadChunks = 64
+	// How long to wait before retrying a blocking operation that failed
+	NetRetryDelay = 10 * time.Millisecond
 )
 
 const (
@@ -28,136 +56,387 @@ const (
 	netStateConnected uint8 = 2
 )
 
+// Configures the network extension on creation
+type NetworkOption func(*Network)
+
 // The network extension
 type Network struct {
 	// Lock on this data structure
-	isLocked atomic.Bool
-	// Internal state
-	state uint8
+	mu sync.Mutex
 	// Universal timeout
 	timeout time.Duration
 	// Port in the "42000" format
 	port string
+	// Port for a data pointer byte of 0
+	basePort int
+	// Host to connect to (empty for the default)
+	targetHost string
+	// Host to listen on (empty for the default)
+	listenHost string
+	// Network family, "tcp4" or "tcp6" (also used for "udp4" and "udp6")
+	family string
+	// Directory of the Unix domain sockets, empty to use TCP or UDP
+	unixDir string
+	// What the program is allowed to reach
+	policy NetPolicy
+	// Hosts that can be used as target or listening host
+	allowedHosts map[string]bool
+	// Ports that can be used (empty to allow all)
+	allowedPorts map[string]bool
+	// Audit log, nil if disabled
+	auditLog io.Writer
+	// Closed networks refuse every operation
+	closed bool
+	// Datagram (UDP) mode
+	datagram bool
+	// Listening socket in datagram mode
+	packetConn *netPacketConn
+	// Read buffer of the datagram sockets, allocated once
+	datagramBuffer []byte
+	// Unread bytes of the last datagram
+	recvCache []byte
+	// Source of the last datagram
+	source net.Addr
 	// Cached listener
 	listener net.Listener
-	// Cached connection
-	conn net.Conn
+	// Receives the connections accepted by the listener, closed once the listener is done
+	accepted chan *netConn
+	// Open connections by handle (nil if closed)
+	conns []*netConn
+	// Handle of the active connection (-1 if none)
+	active int
+	// Error code of the last failed operation
+	lastErr uint8
+	// The error that ends the program (see Err), nil if none
+	err error
 	// Send Cache
 	sendCache []byte
+	// Incremented when the connections and the cache are reset, operations
+	// waiting without the lock drop their results if it changed
+	epoch int
+	// Opens listeners and connections
+	transport Transport
+	// TLS configuration, nil if disabled
+	tlsConfig *tls.Config
 }
 
-// Lock
-func (n *Network) lock(lowPriority bool) {
-	for !n.isLocked.CompareAndSwap(false, true) {
-		if lowPriority {
-			runtime.Gosched()
-		}
+// Returns the internal state
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) state() uint8 {
+	if n.activeConn() != nil {
+		return netStateConnected
 	}
-}
-
-// Unlock
-func (n *Network) unlock() {
-	n.isLocked.Store(false)
+	if n.listener != nil || n.packetConn != nil {
+		return netStateListening
+	}
+	return netStateIdle
 }
 
 // Closes all connections and resets the cache
 // clearCache controls if the cache is reset or not
 // NOTE: Requires the lock to be held by the current process
 func (n *Network) reset(clearCache bool) {
-	if n.state == netStateListening {
-		n.listener.Close()
-	}
-	if n.state == netStateConnected {
-		n.conn.Close()
+	n.epoch++
+	n.stopListening()
+	for handle := range n.conns {
+		n.closeConn(handle)
 	}
+	n.conns = nil
 	if clearCache {
 		n.sendCache = []byte{}
 	}
-	n.state = netStateIdle
 }
 
-// Sets up the listener
+// Closes the listener or the datagram socket
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) stopListening() {
+	if n.packetConn != nil {
+		n.packetConn.Close()
+		n.packetConn = nil
+		n.recvCache = nil
+		n.source = nil
+	}
+	if n.listener != nil {
+		n.listener.Close()
+		// Close the connections accepted while closing the listener
+		go func(accepted chan *netConn) {
+			for conn := range accepted {
+				conn.Close()
+			}
+		}(n.accepted)
+		n.listener = nil
+		n.accepted = nil
+	}
+}
+
+// Sets up the listener, it keeps accepting connections until closed
 // Returns true on success, false otherwise
 // NOTE: Requires the lock to be held by the current process
 func (n *Network) startListening() bool {
 	// Init
-	n.reset(true)
+	n.sendCache = []byte{}
 	// Setup listener
-	listener, err := net.Listen("tcp4", NetListenAddr+n.port)
+	address := n.listenAddress()
+	if n.checkPolicy(n.listenAddr()) != nil {
+		n.audit("deny", address, 0)
+		n.lastErr = NetErrDenied
+		return false
+	}
+	listener, err := n.transport.Listen(n.networkName(), address)
+	if err != nil && n.unixDir != "" && n.removeStaleSocket(address) {
+		listener, err = n.transport.Listen(n.networkName(), address)
+	}
 	if err != nil {
+		n.fail(err, NetErrRefused)
 		return false
 	}
-	// Accept connections
-	cAccepting := make(chan bool)
-	go func(listener net.Listener) {
-		cAccepting <- true
-		conn, err := listener.Accept()
-		if errors.Is(err, net.ErrClosed) {
-			// This listener was closed
-			return
-		}
-		listener.Close()
-		if err == nil {
-			n.lock(false)
-			n.state = netStateConnected
-			n.conn = conn
-			n.listener = nil
-			n.unlock()
+	n.audit("listen", address, 0)
+	listener = n.serverTLS(listener)
+	// Accept connections, reading from them right away
+	accepted := make(chan *netConn, NetMaxConns)
+	timeout := n.timeout
+	go func() {
+		defer close(accepted)
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				// This listener was closed
+				return
+			}
+			accepted <- newNetConn(conn, timeout)
 		}
-	}(listener)
-	// If ready to accept, finalize setup and terminate
-	<-cAccepting
+	}()
 	n.listener = listener
-	n.state = netStateListening
+	n.accepted = accepted
 	return true
 }
 
-// Setup a connection to the current target
-// Returns true if successful, false otherwise
+// Adds a connection with the lowest free handle
+// It becomes the active connection if there is none
+// Returns false if there are no free handles
 // NOTE: Requires the lock to be held by the current process
+func (n *Network) addConn(conn *netConn) bool {
+	handle := 0
+	for handle < len(n.conns) && n.conns[handle] != nil {
+		handle++
+	}
+	if handle == NetMaxConns {
+		conn.Close()
+		return false
+	}
+	if handle == len(n.conns) {
+		n.conns = append(n.conns, conn)
+	} else {
+		n.conns[handle] = conn
+	}
+	if n.activeConn() == nil {
+		n.active = handle
+	}
+	return true
+}
+
+// Adds a connection accepted by the listener
+// If ok is false the listener stopped
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) acceptConnection(conn *netConn, ok bool) {
+	if !ok {
+		n.listener = nil
+		n.accepted = nil
+		return
+	}
+	if n.addConn(conn) {
+		n.audit("accept", conn.RemoteAddr().String(), 0)
+	}
+}
+
+// Adds the connections accepted by the listener, if there are any
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) checkAccepted() {
+	for n.accepted != nil {
+		select {
+		case conn, ok := <-n.accepted:
+			n.acceptConnection(conn, ok)
+		default:
+			return
+		}
+	}
+}
+
+// Waits up to the timeout for the listener to accept a connection
+// Returns true if connected, false otherwise (setting the last error)
+// NOTE: Requires the lock to be held by the current process, it's released while waiting
+func (n *Network) waitAccepted() bool {
+	accepted := n.accepted
+	timer := time.NewTimer(n.timeout)
+	defer timer.Stop()
+	n.mu.Unlock()
+	select {
+	case conn, ok := <-accepted:
+		n.mu.Lock()
+		if n.accepted != accepted {
+			// The listener was closed while waiting
+			if ok {
+				conn.Close()
+			}
+			n.lastErr = NetErrClosed
+			return false
+		}
+		n.acceptConnection(conn, ok)
+		if n.activeConn() == nil {
+			n.lastErr = NetErrClosed
+			return false
+		}
+		return true
+	case <-timer.C:
+		n.mu.Lock()
+		n.lastErr = NetErrTimeout
+		return false
+	}
+}
+
+// Returns the active connection, or nil if there is none
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) activeConn() *netConn {
+	if n.active < 0 || n.active >= len(n.conns) {
+		return nil
+	}
+	return n.conns[n.active]
+}
+
+// Activates the open connection with the lowest handle if there is no active connection
+// Returns the active connection, or nil if there is none
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) activateConn() *netConn {
+	if conn := n.activeConn(); conn != nil {
+		return conn
+	}
+	for handle, conn := range n.conns {
+		if conn != nil {
+			n.active = handle
+			return conn
+		}
+	}
+	return nil
+}
+
+// Closes the given connection if it's still open
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) dropConn(conn *netConn) {
+	for handle, c := range n.conns {
+		if c == conn {
+			n.closeConn(handle)
+			return
+		}
+	}
+}
+
+// Closes the connection with the given handle
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) closeConn(handle int) {
+	if handle < 0 || handle >= len(n.conns) || n.conns[handle] == nil {
+		return
+	}
+	n.conns[handle].Close()
+	n.conns[handle] = nil
+	if handle == n.active {
+		n.active = -1
+	}
+}
+
+// Setup a new active connection to the current target
+// Returns true if successful, false otherwise
+// NOTE: Requires the lock to be held by the current process, it's released while connecting
 func (n *Network) setupConnection() bool {
-	// Init (but don't reset the send cache)
-	n.reset(false)
 	// Connect
-	conn, err := net.DialTimeout("tcp4", NetTargetAddr+n.port, n.timeout)
+	address := n.targetAddress()
+	if n.checkPolicy(n.targetAddr()) != nil {
+		n.audit("deny", address, 0)
+		n.lastErr = NetErrDenied
+		return false
+	}
+	network, timeout, epoch := n.networkName(), n.timeout, n.epoch
+	n.mu.Unlock()
+	conn, err := n.transport.Dial(network, address, timeout)
+	n.mu.Lock()
 	if err != nil {
+		n.audit("connect-error", address, 0)
+		n.fail(err, NetErrRefused)
+		return false
+	}
+	if n.epoch != epoch {
+		// The network was reset while connecting
+		conn.Close()
+		n.lastErr = NetErrClosed
+		return false
+	}
+	n.active = -1
+	if !n.addConn(newNetConn(n.clientTLS(conn), timeout)) {
+		n.lastErr = NetErrRefused
 		return false
 	}
-	n.state = netStateConnected
-	n.conn = conn
+	n.audit("connect", address, 0)
 	return true
 }
 
 // Sets the timeout corresponding to the given byte
 // Formula: timeout = 0.1 second * b
 func (n *Network) SetTimeout(b byte) {
-	n.lock(false)
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	if b == 0 {
 		// Special case: if timeout is 0, receive/send is blocking until success
 		n.timeout = NetLongTimeout
 		return
 	}
 	n.timeout = time.Duration(b) * (time.Second / 10)
-	n.unlock()
 }
 
 // Sets the port corresponding to the given byte
-// Formula: port = 24000 + b
+// Formula: port = base port (42000 by default) + b
 func (n *Network) SetPort(b byte) {
-	// Get lock
-	n.lock(false)
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	// Update port
-	n.port = byteToPort(b)
+	n.port = byteToPort(n.basePort, b)
 	// Reset
 	n.reset(true)
-	// Unlock
-	n.unlock()
+}
+
+// Selects the active connection by handle
+// Returns true if successful, false if the handle is not open
+func (n *Network) SelectConn(b byte) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.checkAccepted()
+	handle := int(b)
+	if handle >= len(n.conns) || n.conns[handle] == nil {
+		return false
+	}
+	n.active = handle
+	return true
+}
+
+// Closes the active connection, or the listener if there is no active connection
+// The send queue is kept
+func (n *Network) CloseConn() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.activeConn() != nil {
+		n.closeConn(n.active)
+		return
+	}
+	n.stopListening()
 }
 
 // Send queue over the network
 // Also removes the data from send cache if successful
-// Assumes lock held by caller and conn is valid
-func (n *Network) send() bool {
+// Assumes lock held by caller and conn is valid, the lock is released while writing
+func (n *Network) send(conn *netConn) bool {
+	if err := n.handshake(conn); err != nil {
+		n.fail(err, errorCode(err))
+		return false
+	}
+	epoch := n.epoch
 	for len(n.sendCache) > 0 {
 		var pkt []byte
 		if len(n.sendCache) > 1024 {
@@ -165,14 +444,20 @@ func (n *Network) send() bool {
 		} else {
 			pkt, n.sendCache = n.sendCache, []byte{}
 		}
-		n.conn.SetDeadline(time.Now().Add(n.timeout))
-		nSent, err := n.conn.Write(pkt)
-		if nSent != len(pkt) {
-			n.sendCache = append(pkt, n.sendCache...)
+		timeout := n.timeout
+		n.mu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(timeout))
+		nSent, err := conn.Write(pkt)
+		n.mu.Lock()
+		n.audit("send", conn.RemoteAddr().String(), nSent)
+		if n.epoch != epoch {
+			// The network was reset while writing, with the send queue
+			n.lastErr = NetErrClosed
 			return false
 		}
-		if err != nil {
-			n.sendCache = append(pkt, n.sendCache...)
+		if nSent != len(pkt) || err != nil {
+			n.sendCache = append(append([]byte{}, pkt...), n.sendCache...)
+			n.fail(err, errorCode(err))
 			return false
 		}
 	}
@@ -181,116 +466,306 @@ func (n *Network) send() bool {
 
 // Same as Push, but doesn't retry and assumes lock is held by caller
 func (n *Network) pushOnce() bool {
-	if n.state == netStateConnected && n.send() {
-		// Used existing connection successful
-		return true
+	n.lastErr = NetErrNone
+	if n.datagram {
+		return n.pushDatagram()
 	}
-	if n.state != netStateIdle {
-		// We're not idle, so reset
-		n.reset(false)
+	n.checkAccepted()
+	if conn := n.activateConn(); conn != nil {
+		if n.send(conn) {
+			// Used existing connection successful
+			return true
+		}
+		// The connection failed, replace it
+		n.dropConn(conn)
 	}
 	if !n.setupConnection() {
 		// We failed to setup a connection
 		return false
 	}
-	return n.send()
+	conn := n.activeConn()
+	if n.send(conn) {
+		return true
+	}
+	if handshakeFailed(conn) {
+		// The connection can't be used anymore
+		n.dropConn(conn)
+	}
+	return false
 }
 
-// Attempts to send queued data to NetTargetAddr at the saved port
+// Attempts to send queued data on the active connection, or to a new
+// connection to the target host at the saved port
 // Returns true if success, false otherwise
 func (n *Network) Push() bool {
-	n.lock(false)
-	defer n.unlock()
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	for {
 		if n.pushOnce() {
 			return true
 		}
-		if n.timeout != NetLongTimeout {
-			// Not a blocking call, return false
+		if n.timeout != NetLongTimeout || n.refused() {
+			// Not a blocking call, or retrying can't succeed, return false
 			return false
 		}
+		n.retryDelay()
 	}
 }
 
+// Waits before retrying a blocking operation, without holding the lock
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) retryDelay() {
+	n.mu.Unlock()
+	time.Sleep(NetRetryDelay)
+	n.mu.Lock()
+}
+
 // Adds data to the send queue
 func (n *Network) QueueSend(b byte) {
-	n.lock(false)
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	n.sendCache = append(n.sendCache, b)
-	n.unlock()
+}
+
+// Reads a byte from the given connection, waiting up to the given timeout (not at all if 0)
+// Assumes lock held by caller and conn is valid, the lock is released while waiting
+func (n *Network) read(conn *netConn, timeout time.Duration) (byte, error) {
+	conn.drain()
+	deadline := time.Now().Add(timeout)
+	for len(conn.pending) == 0 && conn.err == nil && time.Now().Before(deadline) {
+		n.wait(conn, time.Until(deadline))
+	}
+	if len(conn.pending) > 0 {
+		b := conn.pending[0]
+		conn.pending = conn.pending[1:]
+		n.audit("receive", conn.RemoteAddr().String(), 1)
+		return b, nil
+	}
+	err := conn.err
+	if err == nil {
+		err = os.ErrDeadlineExceeded
+	}
+	n.fail(err, errorCode(err))
+	return 0, err
+}
+
+// Waits up to timeout for the next bytes received by conn
+// NOTE: Requires the lock to be held by the current process, it's released while waiting
+func (n *Network) wait(conn *netConn, timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	n.mu.Unlock()
+	select {
+	case chunk, ok := <-conn.chunks:
+		n.mu.Lock()
+		conn.add(chunk, ok)
+	case <-timer.C:
+		n.mu.Lock()
+	}
 }
 
 // Internal version of receive
 // Requires caller to held lock and only tries once
 // Returns received data or false
 func (n *Network) receiveOnce() (byte, bool) {
-	singleByte := make([]byte, 1)
-	if n.state == netStateConnected {
+	n.lastErr = NetErrNone
+	if n.datagram {
+		return n.receiveDatagram(n.timeout)
+	}
+	n.checkAccepted()
+	if conn := n.activateConn(); conn != nil {
 		// Try receiving now
-		n.conn.SetDeadline(time.Now().Add(n.timeout))
-		nSent, err := n.conn.Read(singleByte)
-		if nSent == 1 && err == nil {
-			return singleByte[0], true
+		b, err := n.read(conn, n.timeout)
+		if err == nil {
+			return b, true
 		}
-	}
-	if n.state != netStateIdle && n.state != netStateListening {
-		n.reset(true)
-	}
-	if n.state != netStateListening && !n.startListening() {
-		return 0, false
-	}
-	timedOut := atomic.Bool{}
-	time.AfterFunc(n.timeout, func() { timedOut.Store(true) })
-	n.unlock()
-	for !timedOut.Load() {
-		n.lock(false)
-		if n.state == netStateIdle {
+		if conn.err == nil {
+			// Nothing was received, but the connection is still open
 			return 0, false
 		}
-		if n.state == netStateConnected {
-			n.conn.SetDeadline(time.Now().Add(n.timeout))
-			nSent, err := n.conn.Read(singleByte)
-			if nSent == 1 && err == nil {
-				return singleByte[0], true
-			}
+		// The connection was closed, wait for the next one
+		n.dropConn(conn)
+		if n.activateConn() != nil {
 			return 0, false
 		}
-		n.unlock()
-		time.Sleep(time.Second / 100)
 	}
-	return 0, false
+	if n.listener == nil && !n.startListening() {
+		return 0, false
+	}
+	if !n.waitAccepted() {
+		return 0, false
+	}
+	b, err := n.read(n.activeConn(), n.timeout)
+	return b, err == nil
 }
 
-// Attempts to receive a byte of data from NetListenAddr at the saved port
+// Attempts to receive a byte of data from the active connection, or from
+// a new connection to the listening host at the saved port
 // Returns the received byte if successful, 0 otherwise
 func (n *Network) Receive() byte {
-	n.lock(false)
-	defer n.unlock()
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	for {
 		b, ok := n.receiveOnce()
 		if ok {
 			return b
 		}
-		if n.timeout != NetLongTimeout {
-			// Not a blocking call, return 0
+		if n.timeout != NetLongTimeout || n.refused() {
+			// Not a blocking call, or retrying can't succeed, return 0
 			return 0
 		}
+		n.retryDelay()
+	}
+}
+
+// Attempts to receive a byte of data from the active connection without waiting
+// Starts listening if there is no connection
+// Returns the received byte and true if successful, 0 and false otherwise
+func (n *Network) Poll() (byte, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.lastErr = NetErrNone
+	if n.datagram {
+		return n.receiveDatagram(0)
+	}
+	n.checkAccepted()
+	conn := n.activateConn()
+	if conn == nil {
+		if n.listener == nil && !n.startListening() {
+			return 0, false
+		}
+		n.lastErr = NetErrTimeout
+		return 0, false
+	}
+	b, err := n.read(conn, 0)
+	if err == nil {
+		return b, true
+	}
+	if conn.err != nil {
+		// The connection was closed
+		n.dropConn(conn)
+	}
+	return 0, false
+}
+
+// Sets the transport used to listen and connect
+func WithTransport(t Transport) NetworkOption {
+	return func(n *Network) {
+		n.transport = t
+	}
+}
+
+// Replaces the network extension
+func WithNetwork(n *Network) ProgramOption {
+	return func(p *Program) {
+		p.Network = n
+	}
+}
+
+// Returns the host to connect to
+func (n *Network) targetAddr() string {
+	if n.targetHost != "" {
+		return n.targetHost
+	}
+	if n.family == "tcp6" {
+		return NetTargetHostIPv6
+	}
+	return NetTargetHost
+}
+
+// Returns the address to connect to
+func (n *Network) targetAddress() string {
+	if n.unixDir != "" {
+		return n.socketPath()
+	}
+	return net.JoinHostPort(n.targetAddr(), n.port)
+}
+
+// Returns the address to listen on
+func (n *Network) listenAddress() string {
+	if n.unixDir != "" {
+		return n.socketPath()
+	}
+	return net.JoinHostPort(n.listenAddr(), n.port)
+}
+
+// Returns the host to listen on
+// With a loopback policy the default is to listen on the loopback host
+func (n *Network) listenAddr() string {
+	if n.listenHost != "" {
+		return n.listenHost
+	}
+	if n.policy == NetPolicyLoopback && n.family == "tcp6" {
+		return NetTargetHostIPv6
+	}
+	if n.policy == NetPolicyLoopback {
+		return NetTargetHost
+	}
+	if n.family == "tcp6" {
+		return NetListenHostIPv6
+	}
+	return NetListenHost
+}
+
+// Sets the host to connect to, it must be allowed
+func WithTargetHost(host string) NetworkOption {
+	return func(n *Network) {
+		n.targetHost = host
+	}
+}
+
+// Sets the host to listen on, it must be allowed
+func WithListenHost(host string) NetworkOption {
+	return func(n *Network) {
+		n.listenHost = host
+	}
+}
+
+// Sets the port for a data pointer byte of 0
+func WithBasePort(port int) NetworkOption {
+	return func(n *Network) {
+		n.basePort = port
+		n.port = byteToPort(port, 0)
+	}
+}
+
+// Uses IPv6, the default hosts become "::1" and "::"
+func WithIPv6() NetworkOption {
+	return func(n *Network) {
+		n.family = "tcp6"
 	}
 }
 
 // Returns network with default values
-func NewNetwork() *Network {
-	return &Network{
-		isLocked:  atomic.Bool{},
-		state:     netStateIdle,
-		timeout:   5 * time.Second,
-		port:      ":42000",
-		listener:  nil,
-		conn:      nil,
-		sendCache: []byte{},
+func NewNetwork(opts ...NetworkOption) *Network {
+	n := &Network{
+		timeout:    5 * time.Second,
+		port:       byteToPort(NetBasePort, 0),
+		basePort:   NetBasePort,
+		targetHost: "",
+		listenHost: "",
+		family:     "tcp4",
+		policy:     NetPolicyLoopback,
+		allowedHosts: map[string]bool{
+			NetTargetHost:     true,
+			NetListenHost:     true,
+			NetTargetHostIPv6: true,
+			NetListenHostIPv6: true,
+		},
+		allowedPorts: map[string]bool{},
+		listener:     nil,
+		conns:        nil,
+		active:       -1,
+		sendCache:    []byte{},
+		transport:    NewSystemTransport(),
+	}
+	for _, opt := range opts {
+		opt(n)
 	}
+	return n
 }
 
-// Convert a byte to a port in the "####" format starting from 42000 to 42255
-func byteToPort(b byte) string {
-	return strconv.FormatInt(int64(b)+42000, 10)
+// Convert a byte to a port in the "####" format starting from base to base+255
+func byteToPort(base int, b byte) string {
+	return strconv.FormatInt(int64(b)+int64(base), 10)
 }
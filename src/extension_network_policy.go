@@ -0,0 +1,170 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrNetDisabled          = errors.New("the network is disabled")
+	ErrNetPortNotAllowed    = errors.New("the network port is not allowed")
+	ErrNetUnknownPolicy     = errors.New("unknown network policy")
+	ErrNetLoopbackForbidden = errors.New("the network host is not a loopback host")
+	ErrNetClosed            = errors.New("the network is closed")
+)
+
+// Governs what the network extension can reach
+type NetPolicy uint8
+
+const (
+	// Only the allowed hosts (see WithAllowedHosts) can be used
+	NetPolicyAllowlist NetPolicy = 0
+	// Only loopback hosts can be used, listening on the loopback host by default (default)
+	NetPolicyLoopback NetPolicy = 1
+	// No connection can be made
+	NetPolicyDisabled NetPolicy = 2
+)
+
+// Maps the policy names to the policies
+var NetPolicies = map[string]NetPolicy{
+	"allowlist": NetPolicyAllowlist,
+	"loopback":  NetPolicyLoopback,
+	"disabled":  NetPolicyDisabled,
+}
+
+// Returns nil if the policy allows using host on the current port
+func (n *Network) checkPolicy(host string) error {
+	if err := n.checkHost(host); err != nil {
+		return err
+	}
+	if n.basePort < 1 || n.basePort > 65535-255 {
+		return ErrNetInvalidPort
+	}
+	if len(n.allowedPorts) > 0 && !n.allowedPorts[n.port] {
+		return ErrNetPortNotAllowed
+	}
+	return nil
+}
+
+// Returns nil if the policy allows using host
+func (n *Network) checkHost(host string) error {
+	if n.closed {
+		return ErrNetClosed
+	}
+	switch n.policy {
+	case NetPolicyAllowlist:
+		if n.unixDir == "" && !n.allowedHosts[host] {
+			return ErrNetHostNotAllowed
+		}
+	case NetPolicyLoopback:
+		if n.unixDir == "" && !isLoopback(host) {
+			return ErrNetLoopbackForbidden
+		}
+	case NetPolicyDisabled:
+		return ErrNetDisabled
+	default:
+		return ErrNetUnknownPolicy
+	}
+	return nil
+}
+
+// Returns an error if the policy forbids the configured hosts or the base port is out of range
+// A disabled network is always valid
+func (n *Network) Validate() error {
+	if n.basePort < 1 || n.basePort > 65535-255 {
+		return ErrNetInvalidPort
+	}
+	if n.policy == NetPolicyDisabled {
+		return nil
+	}
+	if err := n.checkHost(n.targetAddr()); err != nil {
+		return err
+	}
+	return n.checkHost(n.listenAddr())
+}
+
+// Returns true if the last operation was refused by the policy or because the
+// network is closed, retrying it would fail the same way
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) refused() bool {
+	return n.lastErr == NetErrDenied || n.closed
+}
+
+// Closes all connections, the transport and the audit log (if they are
+// io.Closers), every later operation is refused
+// Returns the first error closing the transport or the audit log, if any
+func (n *Network) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return nil
+	}
+	n.reset(true)
+	n.closed = true
+	var err error
+	if closer, ok := n.transport.(io.Closer); ok {
+		err = closer.Close()
+	}
+	if closer, ok := n.auditLog.(io.Closer); ok {
+		if logErr := closer.Close(); err == nil {
+			err = logErr
+		}
+	}
+	return err
+}
+
+// Writes an entry to the audit log: timestamp, event, peer and bytes count
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) audit(event string, peer string, count int) {
+	if n.auditLog == nil {
+		return
+	}
+	fmt.Fprintf(n.auditLog, "%s %s %s %d\n", time.Now().UTC().Format(time.RFC3339Nano), event, peer, count)
+}
+
+// Sets the network policy
+func WithPolicy(policy NetPolicy) NetworkOption {
+	return func(n *Network) {
+		n.policy = policy
+	}
+}
+
+// Allows hosts to be used as target or listening host
+// By default only the default hosts are allowed
+func WithAllowedHosts(hosts ...string) NetworkOption {
+	return func(n *Network) {
+		for _, host := range hosts {
+			n.allowedHosts[host] = true
+		}
+	}
+}
+
+// Restricts the ports that can be used, by default all ports are allowed
+func WithAllowedPorts(ports ...int) NetworkOption {
+	return func(n *Network) {
+		for _, port := range ports {
+			n.allowedPorts[strconv.Itoa(port)] = true
+		}
+	}
+}
+
+// Logs every listen, accept, connect, send, receive and denied operation to w
+// w is closed with the network if it's an io.Closer
+func WithAuditLog(w io.Writer) NetworkOption {
+	return func(n *Network) {
+		n.auditLog = w
+	}
+}
+
+// Returns true if host is a loopback host
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
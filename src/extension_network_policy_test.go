@@ -0,0 +1,158 @@
+package interpreter
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+* Tests
+**/
+
+func TestNetworkPolicy(t *testing.T) {
+	testCases := []struct {
+		opts      []NetworkOption
+		port      byte
+		addresses []string
+		err       error
+	}{
+		{[]NetworkOption{WithPolicy(NetPolicyDisabled)}, 1, []string{}, nil},
+		{[]NetworkOption{WithPolicy(NetPolicyLoopback)}, 1, []string{"tcp4 127.0.0.1:42001", "tcp4 127.0.0.1:42001"}, nil},
+		{[]NetworkOption{WithPolicy(NetPolicyLoopback), WithIPv6()}, 1, []string{"tcp6 [::1]:42001", "tcp6 [::1]:42001"}, nil},
+		{[]NetworkOption{WithPolicy(NetPolicyLoopback), WithAllowedHosts("10.0.0.2"), WithTargetHost("10.0.0.2")}, 1, []string{"tcp4 127.0.0.1:42001"}, ErrNetLoopbackForbidden},
+		{[]NetworkOption{WithPolicy(NetPolicyLoopback), WithListenHost("0.0.0.0")}, 1, []string{"tcp4 127.0.0.1:42001"}, ErrNetLoopbackForbidden},
+		{[]NetworkOption{WithAllowedPorts(42001)}, 1, []string{"tcp4 127.0.0.1:42001", "tcp4 127.0.0.1:42001"}, nil},
+		{[]NetworkOption{WithAllowedPorts(42001)}, 2, []string{}, nil},
+		{[]NetworkOption{WithPolicy(NetPolicy(42))}, 1, []string{}, ErrNetUnknownPolicy},
+	}
+	for _, test := range testCases {
+		transport := &addressTransport{}
+		n := NewNetwork(append(test.opts, WithTransport(transport))...)
+		if err := n.Validate(); err != test.err {
+			t.Fatalf("Expected %v from Validate, instead got %v", test.err, err)
+		}
+		n.SetTimeout(1)
+		n.SetPort(test.port)
+		n.QueueSend(1)
+		n.Push()
+		n.Receive()
+		if strings.Join(transport.addresses, ",") != strings.Join(test.addresses, ",") {
+			t.Fatalf("Expected addresses %q, instead got %q", test.addresses, transport.addresses)
+		}
+	}
+}
+
+func TestNetworkAuditLog(t *testing.T) {
+	transport := NewMemoryTransport()
+	log := bytes.NewBuffer(make([]byte, 0, 1024))
+	n := NewNetwork(WithTransport(transport), WithAuditLog(log), WithAllowedPorts(42001))
+	n.SetTimeout(10)
+	n.SetPort(2)
+	n.QueueSend(1)
+	n.Push()
+	n.SetPort(1)
+	go func() {
+		// Send a byte and read the reply
+		for {
+			conn, err := transport.Dial("tcp4", "127.0.0.1:42001", time.Second)
+			if err == nil {
+				conn.Write([]byte{7})
+				conn.Read(make([]byte, 2))
+				conn.Close()
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	if b := n.Receive(); b != 7 {
+		t.Fatalf("Expected to receive 7, instead got %d", b)
+	}
+	n.QueueSend(8)
+	n.QueueSend(9)
+	if !n.Push() {
+		t.Fatal("Failed to send the reply")
+	}
+	n.QueueSend(1)
+	n.SetPort(1)
+	n.Push()
+	expected := []string{
+		`deny 127\.0\.0\.1:42002 0`,
+		`listen 127\.0\.0\.1:42001 0`,
+		`accept pipe 0`,
+		`receive pipe 1`,
+		`send pipe 2`,
+		`connect-error 127\.0\.0\.1:42001 0`,
+	}
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d audit entries, instead got %q", len(expected), lines)
+	}
+	for i, line := range lines {
+		pattern := regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(\.\d+)?Z ` + expected[i] + `$`)
+		if !pattern.MatchString(line) {
+			t.Errorf("Expected audit entry %d to match %q, instead got %q", i, pattern, line)
+		}
+	}
+}
+
+func TestNetworkPolicyBlocking(t *testing.T) {
+	// With the blocking timeout, refused operations must still fail right away
+	for _, opts := range [][]NetworkOption{
+		{WithPolicy(NetPolicyDisabled)},
+		{WithAllowedPorts(42001)},
+	} {
+		p, err := NewProgram(strings.NewReader(`tl:net *? ^;`), WithNetwork(NewNetwork(opts...)))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		done := make(chan error)
+		go func() {
+			done <- p.Run(10)
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Expected no error on Run, instead got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the refused operations not to block")
+		}
+		if p.Network.lastErr != NetErrDenied {
+			t.Fatalf("Expected a denied error, instead got %d", p.Network.lastErr)
+		}
+	}
+}
+
+// An audit log that records if it was closed
+type closingLog struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (l *closingLog) Close() error {
+	l.closed = true
+	return nil
+}
+
+func TestNetworkClose(t *testing.T) {
+	log := &closingLog{}
+	p, err := NewProgram(strings.NewReader(`tl:net ?`),
+		WithNetwork(NewNetwork(WithTransport(NewMemoryTransport()), WithAuditLog(log))))
+	if err != nil {
+		t.Fatalf("Failed to load test program: %v", err)
+	}
+	p.Network.SetTimeout(1)
+	if err := p.Run(10); err != nil || p.Network.state() != netStateListening {
+		t.Fatalf("Expected to listen, instead got state %d (%v)", p.Network.state(), err)
+	}
+	if err := p.Close(); err != nil || !log.closed || p.Network.state() != netStateIdle {
+		t.Fatalf("Expected to close the listener and the audit log, instead got state %d (%v)", p.Network.state(), err)
+	}
+	p.Network.SetTimeout(0)
+	if p.Network.Receive() != 0 || p.Network.Push() || p.Network.lastErr != NetErrDenied {
+		t.Fatalf("Expected a closed network to refuse every operation, instead got error %d", p.Network.lastErr)
+	}
+}
@@ -0,0 +1,556 @@
+package interpreter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrReplayFormat   = errors.New("invalid network log")
+	ErrReplayMismatch = errors.New("the network operation does not match the log")
+	ErrReplayFailed   = errors.New("the logged network operation failed")
+)
+
+// The first line of a network log
+const NetLogHeader = "tlnet 1"
+
+// A network log records one operation per line, in the order they happened:
+//
+//	<operation> <id> <address> <result> <data>
+//
+// Operations are listen, accept, dial, read, write, listen-packet,
+// read-from and write-to. The id identifies the listener or connection
+// (numbered from 0 in order of creation), the address is the requested
+// address (the remote address for read-from and write-to, "-" otherwise),
+// the result is ok, timeout, eof, closed, refused or error, and the data
+// is the hex encoded bytes read or written ("-" if none).
+type netRecord struct {
+	op      string
+	id      int
+	address string
+	result  string
+	data    []byte
+}
+
+// Returns the log result for an error
+func recordResult(err error) string {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, io.EOF):
+		return "eof"
+	case errors.Is(err, net.ErrClosed) || errors.Is(err, io.ErrClosedPipe):
+		return "closed"
+	case errors.Is(err, ErrTransportRefused):
+		return "refused"
+	}
+	return "error"
+}
+
+// Returns the error for a log result
+func resultError(result string) error {
+	switch result {
+	case "ok":
+		return nil
+	case "timeout":
+		return os.ErrDeadlineExceeded
+	case "eof":
+		return io.EOF
+	case "closed":
+		return net.ErrClosed
+	case "refused":
+		return ErrTransportRefused
+	}
+	return ErrReplayFailed
+}
+
+// Returns the log line of a record
+func (r netRecord) String() string {
+	data := "-"
+	if len(r.data) > 0 {
+		data = hex.EncodeToString(r.data)
+	}
+	return fmt.Sprintf("%s %d %s %s %s", r.op, r.id, r.address, r.result, data)
+}
+
+// Parses a log line
+func parseRecord(line string) (netRecord, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 5 {
+		return netRecord{}, ErrReplayFormat
+	}
+	id, err := strconv.Atoi(fields[1])
+	if err != nil || id < 0 {
+		return netRecord{}, ErrReplayFormat
+	}
+	record := netRecord{op: fields[0], id: id, address: fields[2], result: fields[3]}
+	if fields[4] != "-" {
+		if record.data, err = hex.DecodeString(fields[4]); err != nil {
+			return netRecord{}, ErrReplayFormat
+		}
+	}
+	return record, nil
+}
+
+/*
+* Recording
+**/
+
+// A transport that logs every operation of another transport
+type RecordingTransport struct {
+	transport Transport
+	// Lock on the log
+	mu  sync.Mutex
+	log io.Writer
+	// Closed transports stop logging
+	closed bool
+	// Next listener or connection id
+	nextID int
+}
+
+// Records the operations of the given transport to the log
+func NewRecordingTransport(transport Transport, log io.Writer) *RecordingTransport {
+	io.WriteString(log, NetLogHeader+"\n")
+	return &RecordingTransport{transport: transport, log: log}
+}
+
+// Stops logging and closes the log if it's an io.Closer, the recorded transport stays open
+// Returns an error
+func (t *RecordingTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	if closer, ok := t.log.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Writes a record to the log
+// Returns the id of the record, a new id if id is negative
+func (t *RecordingTransport) record(op string, id int, address string, err error, data []byte) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if id < 0 {
+		id = t.nextID
+		t.nextID++
+	}
+	if t.closed {
+		return id
+	}
+	io.WriteString(t.log, netRecord{op, id, address, recordResult(err), data}.String()+"\n")
+	return id
+}
+
+func (t *RecordingTransport) Listen(network, address string) (net.Listener, error) {
+	listener, err := t.transport.Listen(network, address)
+	id := t.record("listen", -1, address, err, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingListener{Listener: listener, transport: t, id: id, address: address}, nil
+}
+
+func (t *RecordingTransport) Dial(network, address string, timeout time.Duration) (net.Conn, error) {
+	conn, err := t.transport.Dial(network, address, timeout)
+	id := t.record("dial", -1, address, err, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingConn{Conn: conn, transport: t, id: id}, nil
+}
+
+// Listens for datagrams if the recorded transport is a PacketTransport
+func (t *RecordingTransport) ListenPacket(network, address string) (net.PacketConn, error) {
+	var pc net.PacketConn
+	err := ErrTransportNoPackets
+	if transport, ok := t.transport.(PacketTransport); ok {
+		pc, err = transport.ListenPacket(network, address)
+	}
+	id := t.record("listen-packet", -1, address, err, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingPacketConn{PacketConn: pc, transport: t, id: id}, nil
+}
+
+// A listener that logs the accepted connections
+type recordingListener struct {
+	net.Listener
+	transport *RecordingTransport
+	id        int
+	address   string
+}
+
+func (l *recordingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		// Closing the listener is not logged
+		return nil, err
+	}
+	id := l.transport.record("accept", -1, l.address, nil, nil)
+	return &recordingConn{Conn: conn, transport: l.transport, id: id}, nil
+}
+
+// A connection that logs the bytes read and written
+type recordingConn struct {
+	net.Conn
+	transport *RecordingTransport
+	id        int
+}
+
+func (c *recordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.transport.record("read", c.id, "-", err, b[:n])
+	return n, err
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.transport.record("write", c.id, "-", err, b[:n])
+	return n, err
+}
+
+// A datagram socket that logs the datagrams read and written
+type recordingPacketConn struct {
+	net.PacketConn
+	transport *RecordingTransport
+	id        int
+}
+
+func (c *recordingPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(b)
+	address := "-"
+	if addr != nil {
+		address = addr.String()
+	}
+	c.transport.record("read-from", c.id, address, err, b[:n])
+	return n, addr, err
+}
+
+func (c *recordingPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	n, err := c.PacketConn.WriteTo(b, addr)
+	c.transport.record("write-to", c.id, addr.String(), err, b[:n])
+	return n, err
+}
+
+/*
+* Replay
+**/
+
+// A transport that plays back a network log without any sockets
+// Operations must happen in the logged order, except for accepted
+// connections and reads (of connections and datagram sockets) which are
+// returned as soon as they are next in the log, while their listener,
+// connection or socket is open
+// Written data must match the logged data
+type ReplayTransport struct {
+	// Lock on the position, signaled when it changes
+	mu   sync.Mutex
+	cond *sync.Cond
+	// Logged operations
+	records []netRecord
+	// Position of the next operation
+	next int
+	// Open listeners and connections by id
+	open map[int]bool
+}
+
+// Reads a network log to play back
+func NewReplayTransport(log io.Reader) (*ReplayTransport, error) {
+	scanner := bufio.NewScanner(log)
+	scanner.Buffer(make([]byte, 64*1024), 4*NetMaxDatagram)
+	if !scanner.Scan() || scanner.Text() != NetLogHeader {
+		return nil, ErrReplayFormat
+	}
+	t := &ReplayTransport{open: map[int]bool{}}
+	t.cond = sync.NewCond(&t.mu)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		record, err := parseRecord(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		t.records = append(t.records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Returns true if every logged operation was played back
+func (t *ReplayTransport) Done() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.next == len(t.records)
+}
+
+// Plays back the next operation if it matches op, id (if not negative), address (if not empty)
+// and written (if not nil), written must start with the logged data
+// Waits for pending accepts and reads to be played back first
+func (t *ReplayTransport) take(op string, id int, address string, written []byte) (netRecord, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.passive() {
+		t.cond.Wait()
+	}
+	if t.next == len(t.records) {
+		return netRecord{}, ErrReplayMismatch
+	}
+	record := t.records[t.next]
+	if record.op != op || (id >= 0 && record.id != id) || (address != "" && record.address != address) {
+		return netRecord{}, ErrReplayMismatch
+	}
+	if written != nil && !bytes.HasPrefix(written, record.data) {
+		return netRecord{}, ErrReplayMismatch
+	}
+	t.next++
+	t.cond.Broadcast()
+	return record, nil
+}
+
+// Returns true if the next operation is an accept or a read played back by an open
+// listener or connection, skipping the ones that can't be played back anymore
+// NOTE: Requires the lock to be held by the current process
+func (t *ReplayTransport) passive() bool {
+	for t.next < len(t.records) {
+		record := t.records[t.next]
+		owner := record.id
+		switch record.op {
+		case "accept":
+			owner = t.listenerOf(record)
+		case "read", "read-from":
+		default:
+			return false
+		}
+		if t.open[owner] {
+			return true
+		}
+		// Nobody can play back this operation
+		t.next++
+	}
+	return false
+}
+
+// Plays back the next operation once it's an accept by listener or a read
+// (or read-from) by connection id, whichever matches op
+// Returns the record, or net.ErrClosed if the listener or connection was closed first
+func (t *ReplayTransport) await(op string, id int) (netRecord, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.open[id] {
+		if t.next < len(t.records) {
+			record := t.records[t.next]
+			if record.op == op && ((op == "accept" && t.listenerOf(record) == id) || (op != "accept" && record.id == id)) {
+				t.next++
+				if op == "accept" {
+					t.open[record.id] = true
+				}
+				t.cond.Broadcast()
+				return record, nil
+			}
+		}
+		t.cond.Wait()
+	}
+	return netRecord{}, net.ErrClosed
+}
+
+// Marks the listener or connection id as closed
+func (t *ReplayTransport) close(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.open, id)
+	t.cond.Broadcast()
+}
+
+// Marks the listener or connection of a record as open
+func (t *ReplayTransport) opened(record netRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.open[record.id] = true
+}
+
+// Returns the id of the last listener on the address of an accept record, -1 if none
+// NOTE: Requires the lock to be held by the current process
+func (t *ReplayTransport) listenerOf(accept netRecord) int {
+	for i := t.next - 1; i >= 0; i-- {
+		if t.records[i].op == "listen" && t.records[i].address == accept.address {
+			return t.records[i].id
+		}
+	}
+	return -1
+}
+
+func (t *ReplayTransport) Listen(network, address string) (net.Listener, error) {
+	record, err := t.take("listen", -1, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := resultError(record.result); err != nil {
+		return nil, err
+	}
+	t.opened(record)
+	return &replayListener{transport: t, id: record.id, address: address}, nil
+}
+
+func (t *ReplayTransport) Dial(network, address string, timeout time.Duration) (net.Conn, error) {
+	record, err := t.take("dial", -1, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := resultError(record.result); err != nil {
+		return nil, err
+	}
+	t.opened(record)
+	return &replayConn{transport: t, id: record.id, remote: memoryAddr(address)}, nil
+}
+
+func (t *ReplayTransport) ListenPacket(network, address string) (net.PacketConn, error) {
+	record, err := t.take("listen-packet", -1, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := resultError(record.result); err != nil {
+		return nil, err
+	}
+	t.opened(record)
+	return &replayPacketConn{transport: t, id: record.id, addr: memoryAddr(address)}, nil
+}
+
+// A listener playing back the accepted connections
+type replayListener struct {
+	transport *ReplayTransport
+	id        int
+	address   string
+}
+
+func (l *replayListener) Accept() (net.Conn, error) {
+	record, err := l.transport.await("accept", l.id)
+	if err != nil {
+		return nil, err
+	}
+	return &replayConn{transport: l.transport, id: record.id, remote: memoryAddr("replay")}, nil
+}
+
+func (l *replayListener) Close() error {
+	l.transport.close(l.id)
+	return nil
+}
+
+func (l *replayListener) Addr() net.Addr {
+	return memoryAddr(l.address)
+}
+
+// A connection playing back the logged reads and writes
+type replayConn struct {
+	transport *ReplayTransport
+	id        int
+	remote    net.Addr
+}
+
+func (c *replayConn) Read(b []byte) (int, error) {
+	record, err := c.transport.await("read", c.id)
+	if err != nil {
+		return 0, err
+	}
+	return copy(b, record.data), resultError(record.result)
+}
+
+func (c *replayConn) Write(b []byte) (int, error) {
+	record, err := c.transport.take("write", c.id, "", b)
+	if err != nil {
+		return 0, err
+	}
+	return len(record.data), resultError(record.result)
+}
+
+func (c *replayConn) Close() error {
+	c.transport.close(c.id)
+	return nil
+}
+
+func (c *replayConn) LocalAddr() net.Addr {
+	return memoryAddr("replay")
+}
+
+func (c *replayConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *replayConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *replayConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *replayConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// A datagram socket playing back the logged datagrams
+type replayPacketConn struct {
+	transport *ReplayTransport
+	id        int
+	addr      net.Addr
+}
+
+func (c *replayPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	record, err := c.transport.await("read-from", c.id)
+	if err != nil {
+		return 0, nil, err
+	}
+	var from net.Addr
+	if record.address != "-" {
+		if from, err = net.ResolveUDPAddr("udp", record.address); err != nil {
+			return 0, nil, ErrReplayFormat
+		}
+	}
+	return copy(b, record.data), from, resultError(record.result)
+}
+
+func (c *replayPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	record, err := c.transport.take("write-to", c.id, addr.String(), b)
+	if err != nil {
+		return 0, err
+	}
+	return len(record.data), resultError(record.result)
+}
+
+func (c *replayPacketConn) Close() error {
+	c.transport.close(c.id)
+	return nil
+}
+
+func (c *replayPacketConn) LocalAddr() net.Addr {
+	return c.addr
+}
+
+func (c *replayPacketConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *replayPacketConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *replayPacketConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,111 @@
+package interpreter
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+/*
+* Tests
+**/
+
+func TestNetworkRecordReplay(t *testing.T) {
+	// Record the server of a session
+	transport := NewMemoryTransport()
+	log := &closingLog{}
+	server, err := NewProgram(strings.NewReader(`tl:net +@ ?.+^;`),
+		WithNetwork(NewNetwork(WithTransport(NewRecordingTransport(transport, log)))))
+	if err != nil {
+		t.Fatalf("Failed to load server program: %v", err)
+	}
+	server.IOWriter = bytes.NewBuffer(make([]byte, 0, 1))
+	client, err := NewProgram(strings.NewReader(`tl:net +@ >,^[;] ?`),
+		WithNetwork(NewNetwork(WithTransport(transport))))
+	if err != nil {
+		t.Fatalf("Failed to load client program: %v", err)
+	}
+	client.IOReader = strings.NewReader("A")
+	serverErr := make(chan error)
+	go func() {
+		serverErr <- server.Run(100)
+	}()
+	if err := client.Run(1000000); err != nil {
+		t.Fatalf("Expected no error from the client, instead got %v", err)
+	}
+	if err := <-serverErr; err != nil {
+		t.Fatalf("Expected no error from the server, instead got %v", err)
+	}
+	expectedLog := NetLogHeader + "\n" +
+		"listen 0 127.0.0.1:42001 ok -\n" +
+		"accept 1 127.0.0.1:42001 ok -\n" +
+		"read 1 - ok 41\n" +
+		"write 1 - ok 42\n"
+	if log.String() != expectedLog {
+		t.Fatalf("Expected the log %q, instead got %q", expectedLog, log.String())
+	}
+	// Closing the program closes the log
+	if err := server.Close(); err != nil || !log.closed {
+		t.Fatalf("Expected the log to be closed, instead got %v (closed: %t)", err, log.closed)
+	}
+	// Replay the server alone
+	for _, test := range []struct {
+		src    string
+		output string
+		done   bool
+		err    error
+	}{
+		{`tl:net +@ ?.+^;`, "A", true, nil},
+		{`tl:net +@ ?.^;.`, "A", false, ErrReplayMismatch},
+	} {
+		replay, err := NewReplayTransport(strings.NewReader(log.String()))
+		if err != nil {
+			t.Fatalf("Failed to read the log: %v", err)
+		}
+		server, err := NewProgram(strings.NewReader(test.src),
+			WithNetwork(NewNetwork(WithTransport(replay))))
+		if err != nil {
+			t.Fatalf("Failed to load server program: %v", err)
+		}
+		output := bytes.NewBuffer(make([]byte, 0, 2))
+		server.IOWriter = output
+		server.Network.SetTimeout(1)
+		if err := server.Run(100); !errors.Is(err, test.err) || (err == nil) != (test.err == nil) {
+			t.Fatalf("Expected %v from the replay of %q, instead got %v", test.err, test.src, err)
+		}
+		if output.String() != test.output || replay.Done() != test.done {
+			t.Fatalf("Expected %q (done: %t) from %q, instead got %q (done: %t)", test.output, test.done, test.src, output.String(), replay.Done())
+		}
+	}
+}
+
+func TestReplayTransportFormat(t *testing.T) {
+	testCases := []struct {
+		log string
+		err error
+	}{
+		{"", ErrReplayFormat},
+		{"tlnet 2\n", ErrReplayFormat},
+		{NetLogHeader + "\n", nil},
+		{NetLogHeader + "\nread 0 - ok\n", ErrReplayFormat},
+		{NetLogHeader + "\nread x - ok -\n", ErrReplayFormat},
+		{NetLogHeader + "\nread 0 - ok 4x\n", ErrReplayFormat},
+		{NetLogHeader + "\ndial 0 127.0.0.1:42001 refused -\n\nread 0 - eof -\n", nil},
+	}
+	for _, test := range testCases {
+		if _, err := NewReplayTransport(strings.NewReader(test.log)); err != test.err {
+			t.Errorf("Expected %v for %q, instead got %v", test.err, test.log, err)
+		}
+	}
+	replay, _ := NewReplayTransport(strings.NewReader(NetLogHeader + "\ndial 0 127.0.0.1:42001 refused -\n"))
+	if _, err := replay.Dial("tcp4", "127.0.0.1:42002", 0); err != ErrReplayMismatch {
+		t.Fatalf("Expected a mismatch for a different address, instead got %v", err)
+	}
+	if _, err := replay.Dial("tcp4", "127.0.0.1:42001", 0); err != ErrTransportRefused {
+		t.Fatalf("Expected the logged error, instead got %v", err)
+	}
+	if _, err := replay.Dial("tcp4", "127.0.0.1:42001", 0); err != ErrReplayMismatch || !replay.Done() {
+		t.Fatalf("Expected a mismatch at the end of the log, instead got %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package interpreter
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// The last operation was successful
+	NetErrNone uint8 = 0
+	// The last operation timed out
+	NetErrTimeout uint8 = 1
+	// The connection or listener could not be opened
+	NetErrRefused uint8 = 2
+	// The connection was closed by the peer
+	NetErrClosed uint8 = 3
+	// The operation was denied by the network policy
+	NetErrDenied uint8 = 4
+	// The operation failed for any other reason
+	NetErrOther uint8 = 5
+)
+
+// A connection and the bytes read ahead of the program
+type netConn struct {
+	net.Conn
+	// Bytes received but not yet read by the program
+	pending []byte
+	// The error returned by the TLS handshake, nil if it succeeded or didn't run yet
+	handshakeErr error
+	// Closed once the TLS handshake returned (right away without TLS)
+	ready chan struct{}
+	// Bytes received in the background, closed once the reader stopped
+	chunks chan netChunk
+	// Closed with the connection, stops the reader
+	done      chan struct{}
+	closeOnce sync.Once
+	// The error that stopped the reader, set once the bytes before it are pending
+	err error
+}
+
+// Bytes received by a connection and the error that ended the read, if any
+type netChunk struct {
+	data []byte
+	err  error
+}
+
+// Returns a connection reading from c in the background
+// A TLS handshake must complete within the timeout
+func newNetConn(c net.Conn, timeout time.Duration) *netConn {
+	conn := &netConn{
+		Conn:   c,
+		ready:  make(chan struct{}),
+		chunks: make(chan netChunk, NetReadChunks),
+		done:   make(chan struct{}),
+	}
+	go conn.readLoop(timeout)
+	return conn
+}
+
+// Completes the TLS handshake, then reads from the connection until it fails
+func (c *netConn) readLoop(timeout time.Duration) {
+	defer close(c.chunks)
+	if tlsConn, ok := c.Conn.(*tls.Conn); ok {
+		tlsConn.SetDeadline(time.Now().Add(timeout))
+		c.handshakeErr = tlsConn.Handshake()
+		tlsConn.SetDeadline(time.Time{})
+	}
+	close(c.ready)
+	if c.handshakeErr != nil {
+		c.push(netChunk{err: c.handshakeErr})
+		return
+	}
+	for {
+		buffer := make([]byte, 1024)
+		nRead, err := c.Conn.Read(buffer)
+		if (nRead > 0 || err != nil) && !c.push(netChunk{data: buffer[:nRead], err: err}) {
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Hands a chunk to the program, unless the connection is closed first
+// Returns true if the chunk was handed, false otherwise
+func (c *netConn) push(chunk netChunk) bool {
+	select {
+	case c.chunks <- chunk:
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+// Closes the connection and stops the reader
+func (c *netConn) Close() error {
+	err := net.ErrClosed
+	c.closeOnce.Do(func() {
+		close(c.done)
+		err = c.Conn.Close()
+	})
+	return err
+}
+
+// Adds a chunk from the reader to the pending bytes, ok is false once the reader stopped
+func (c *netConn) add(chunk netChunk, ok bool) {
+	c.pending = append(c.pending, chunk.data...)
+	if !ok && chunk.err == nil {
+		chunk.err = net.ErrClosed
+	}
+	if chunk.err != nil && c.err == nil {
+		c.err = chunk.err
+	}
+}
+
+// Moves the bytes received so far to the pending bytes, without waiting
+func (c *netConn) drain() {
+	for c.err == nil {
+		select {
+		case chunk, ok := <-c.chunks:
+			c.add(chunk, ok)
+		default:
+			return
+		}
+	}
+}
+
+// Returns the error code for a failed operation
+func errorCode(err error) uint8 {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return NetErrTimeout
+	}
+	if err == nil || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.ErrClosedPipe) || errors.Is(err, net.ErrClosed) {
+		return NetErrClosed
+	}
+	return NetErrOther
+}
+
+// Records the error code of a failed operation
+// A replay mismatch is also kept to end the program (see Err)
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) fail(err error, code uint8) {
+	if errors.Is(err, ErrReplayMismatch) && n.err == nil {
+		n.err = err
+	}
+	n.lastErr = code
+}
+
+// Returns the error that ends the program, if any: a replayed operation
+// that doesn't match the log (see ErrReplayMismatch)
+func (n *Network) Err() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.err
+}
+
+// Returns the number of bytes that can be received without waiting, up to 255
+// A connection that ended with no bytes left is closed, with its error recorded
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) available() int {
+	count := len(n.recvCache)
+	if conn := n.activateConn(); conn != nil {
+		conn.drain()
+		count = len(conn.pending)
+		if count == 0 && conn.err != nil {
+			n.fail(conn.err, errorCode(conn.err))
+			n.dropConn(conn)
+		}
+	}
+	if count > 255 {
+		return 255
+	}
+	return count
+}
+
+// Returns the status of the network extension: the state, the number of
+// bytes that can be received without waiting, and the last error code
+func (n *Network) Status() []byte {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.checkAccepted()
+	available := n.available()
+	return []byte{n.state(), byte(available), n.lastErr}
+}
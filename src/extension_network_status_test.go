@@ -0,0 +1,111 @@
+package interpreter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+/*
+* Tests
+**/
+
+func TestNetworkStatus(t *testing.T) {
+	transport := NewMemoryTransport()
+	n := NewNetwork(WithTransport(transport))
+	if status := n.Status(); !bytes.Equal(status, []byte{netStateIdle, 0, NetErrNone}) {
+		t.Fatalf("Expected an idle status, instead got %d", status)
+	}
+	n.SetTimeout(1)
+	n.SetPort(1)
+	n.Receive()
+	if status := n.Status(); !bytes.Equal(status, []byte{netStateListening, 0, NetErrTimeout}) {
+		t.Fatalf("Expected a listening status after a timeout, instead got %d", status)
+	}
+	client, err := transport.Dial("tcp4", "127.0.0.1:42001", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	go client.Write([]byte("ab"))
+	status := n.Status()
+	for status[1] != 2 {
+		time.Sleep(time.Millisecond)
+		status = n.Status()
+	}
+	if !bytes.Equal(status, []byte{netStateConnected, 2, NetErrTimeout}) {
+		t.Fatalf("Expected a connected status with 2 bytes available, instead got %d", status)
+	}
+	if b := n.Receive(); b != 'a' {
+		t.Fatalf("Expected to receive %q, instead got %q", 'a', b)
+	}
+	if status := n.Status(); !bytes.Equal(status, []byte{netStateConnected, 1, NetErrNone}) {
+		t.Fatalf("Expected a connected status with 1 byte available, instead got %d", status)
+	}
+	client.Close()
+	if b := n.Receive(); b != 'b' {
+		t.Fatalf("Expected to receive %q after the client closed, instead got %q", 'b', b)
+	}
+	// The status reports the closed connection once all its bytes were received
+	deadline := time.Now().Add(5 * time.Second)
+	for status = n.Status(); status[0] == netStateConnected && time.Now().Before(deadline); status = n.Status() {
+		time.Sleep(time.Millisecond)
+	}
+	if !bytes.Equal(status, []byte{netStateListening, 0, NetErrClosed}) {
+		t.Fatalf("Expected a listening status after the client closed, instead got %d", status)
+	}
+	// Checking the status doesn't wait for bytes
+	if _, err := transport.Dial("tcp4", "127.0.0.1:42001", time.Second); err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	for status = n.Status(); status[0] != netStateConnected && time.Now().Before(deadline); status = n.Status() {
+		time.Sleep(time.Millisecond)
+	}
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		status = n.Status()
+	}
+	if status[0] != netStateConnected || status[1] != 0 || time.Since(start) >= 100*time.Millisecond {
+		t.Fatalf("Expected a connected status right away, instead got %d after %s", status, time.Since(start)/100)
+	}
+	// Closing the connection and then the listener keeps the send queue
+	n.QueueSend(1)
+	n.CloseConn()
+	if status := n.Status(); status[0] != netStateListening {
+		t.Fatalf("Expected to keep listening after closing the connection, instead got %d", status)
+	}
+	n.CloseConn()
+	if status := n.Status(); status[0] != netStateIdle || len(n.sendCache) != 1 {
+		t.Fatalf("Expected an idle status with 1 queued byte, instead got %d with %d queued bytes", status, len(n.sendCache))
+	}
+	if n.Push() {
+		t.Fatal("Expected the send to fail without a listener")
+	}
+	if status := n.Status(); status[2] != NetErrRefused {
+		t.Fatalf("Expected a refused error, instead got %d", status)
+	}
+	n = NewNetwork(WithTransport(transport), WithPolicy(NetPolicyDisabled))
+	n.Push()
+	if status := n.Status(); status[2] != NetErrDenied {
+		t.Fatalf("Expected a denied error, instead got %d", status)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	testCases := []struct {
+		err  error
+		code uint8
+	}{
+		{os.ErrDeadlineExceeded, NetErrTimeout},
+		{io.EOF, NetErrClosed},
+		{io.ErrClosedPipe, NetErrClosed},
+		{errors.New("test"), NetErrOther},
+	}
+	for _, test := range testCases {
+		if code := errorCode(test.err); code != test.code {
+			t.Errorf("Expected code %d for %v, instead got %d", test.code, test.err, code)
+		}
+	}
+}
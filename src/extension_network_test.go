@@ -2,6 +2,8 @@ package interpreter
 
 import (
 	"fmt"
+	"net"
+	"strings"
 	"testing"
 	"time"
 )
@@ -15,7 +17,7 @@ func TestNetwork(t *testing.T) {
 	if n == nil {
 		t.Fatal("Failed to setup network: null pointer")
 	}
-	if n.port != ":42000" || n.timeout != time.Second*5 {
+	if n.port != "42000" || n.timeout != time.Second*5 {
 		t.Fatalf("Unexpected default values: port:%q, timeout:%s", n.port, n.timeout.String())
 	}
 	n.SetPort(1)
@@ -29,9 +31,270 @@ func TestNetwork(t *testing.T) {
 	}
 }
 
+// Records the addresses used by the network and refuses all connections
+type addressTransport struct {
+	addresses []string
+}
+
+func (t *addressTransport) Listen(network, address string) (net.Listener, error) {
+	t.addresses = append(t.addresses, network+" "+address)
+	return nil, ErrTransportRefused
+}
+
+func (t *addressTransport) Dial(network, address string, timeout time.Duration) (net.Conn, error) {
+	t.addresses = append(t.addresses, network+" "+address)
+	return nil, ErrTransportRefused
+}
+
+func (t *addressTransport) ListenPacket(network, address string) (net.PacketConn, error) {
+	t.addresses = append(t.addresses, network+" "+address)
+	return nil, ErrTransportRefused
+}
+
+func TestNetworkAddresses(t *testing.T) {
+	testCases := []struct {
+		opts      []NetworkOption
+		addresses []string
+		err       error
+	}{
+		{[]NetworkOption{}, []string{"tcp4 127.0.0.1:42001", "tcp4 127.0.0.1:42001"}, nil},
+		{[]NetworkOption{WithIPv6()}, []string{"tcp6 [::1]:42001", "tcp6 [::1]:42001"}, nil},
+		{[]NetworkOption{WithPolicy(NetPolicyAllowlist)}, []string{"tcp4 127.0.0.1:42001", "tcp4 0.0.0.0:42001"}, nil},
+		{[]NetworkOption{WithPolicy(NetPolicyAllowlist), WithIPv6()}, []string{"tcp6 [::1]:42001", "tcp6 [::]:42001"}, nil},
+		{[]NetworkOption{WithBasePort(5000)}, []string{"tcp4 127.0.0.1:5001", "tcp4 127.0.0.1:5001"}, nil},
+		{[]NetworkOption{WithTargetHost("10.0.0.2"), WithListenHost("10.0.0.1")}, []string{}, ErrNetLoopbackForbidden},
+		{[]NetworkOption{WithPolicy(NetPolicyAllowlist), WithTargetHost("10.0.0.2"), WithListenHost("10.0.0.1")}, []string{}, ErrNetHostNotAllowed},
+		{[]NetworkOption{WithPolicy(NetPolicyAllowlist), WithTargetHost("10.0.0.2"), WithListenHost("10.0.0.1"), WithAllowedHosts("10.0.0.1", "10.0.0.2")},
+			[]string{"tcp4 10.0.0.2:42001", "tcp4 10.0.0.1:42001"}, nil},
+		{[]NetworkOption{WithPolicy(NetPolicyAllowlist), WithAllowedHosts("fd00::2"), WithTargetHost("fd00::2"), WithIPv6()}, []string{"tcp6 [fd00::2]:42001", "tcp6 [::]:42001"}, nil},
+		{[]NetworkOption{WithBasePort(65300)}, []string{}, ErrNetInvalidPort},
+	}
+	for _, test := range testCases {
+		transport := &addressTransport{}
+		n := NewNetwork(append(test.opts, WithTransport(transport))...)
+		if err := n.Validate(); err != test.err {
+			t.Fatalf("Expected %v from Validate, instead got %v", test.err, err)
+		}
+		n.SetTimeout(1)
+		n.SetPort(1)
+		n.QueueSend(1)
+		n.Push()
+		n.Receive()
+		if strings.Join(transport.addresses, ",") != strings.Join(test.addresses, ",") {
+			t.Fatalf("Expected addresses %q, instead got %q", test.addresses, transport.addresses)
+		}
+	}
+}
+
+func TestNetworkReceiveWakeUp(t *testing.T) {
+	transport := NewMemoryTransport()
+	n := NewNetwork(WithTransport(transport))
+	n.SetPort(3)
+	n.SetTimeout(100)
+	go func() {
+		// Connect as soon as the network is listening
+		for {
+			conn, err := transport.Dial("tcp4", "127.0.0.1:42003", time.Second)
+			if err == nil {
+				conn.Write([]byte{42})
+				conn.Close()
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	start := time.Now()
+	if b := n.Receive(); b != 42 {
+		t.Fatalf("Expected to receive 42, instead got %d", b)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Expected to receive right after the connection, instead took %s", elapsed)
+	}
+}
+
+func TestNetworkLockReleased(t *testing.T) {
+	n := NewNetwork(WithTransport(NewMemoryTransport()))
+	n.SetTimeout(0)
+	received := make(chan byte)
+	go func() {
+		received <- n.Receive()
+	}()
+	// Wait for the blocking receive to listen
+	for n.Status()[0] != netStateListening {
+		time.Sleep(time.Millisecond)
+	}
+	// Other calls must not wait for the receive
+	done := make(chan bool)
+	go func() {
+		n.Status()
+		n.Close()
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Status and Close not to wait for the blocking receive")
+	}
+	select {
+	case b := <-received:
+		if b != 0 || n.lastErr != NetErrClosed {
+			t.Fatalf("Expected the receive to fail once closed, instead got %d and error %d", b, n.lastErr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the blocking receive to end once closed")
+	}
+}
+
+func TestNetworkStress(t *testing.T) {
+	transport := NewMemoryTransport()
+	n := NewNetwork(WithTransport(transport))
+	n.SetTimeout(1)
+	done := make(chan bool)
+	for i := 0; i < 4; i++ {
+		go func(i int) {
+			for j := 0; j < 25; j++ {
+				switch (i + j) % 5 {
+				case 0:
+					n.SetPort(byte(j % 2))
+				case 1:
+					n.SetTimeout(1)
+				case 2:
+					n.QueueSend(byte(j))
+				case 3:
+					n.Push()
+				case 4:
+					n.Receive()
+				}
+			}
+			done <- true
+		}(i)
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+	n.SetPort(0)
+	if n.state() != netStateIdle || len(n.sendCache) != 0 {
+		t.Fatalf("Expected an idle network after setting the port, instead got state %d with %d queued bytes", n.state(), len(n.sendCache))
+	}
+}
+
+func TestNetworkConnections(t *testing.T) {
+	transport := NewMemoryTransport()
+	n := NewNetwork(WithTransport(transport))
+	n.SetTimeout(50)
+	n.SetPort(1)
+	// Three clients connect in order, each one sends its handle plus one
+	clients := make(chan net.Conn, 3)
+	go func() {
+		for i := 0; i < 3; {
+			conn, err := transport.Dial("tcp4", "127.0.0.1:42001", time.Second)
+			if err != nil {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			go conn.Write([]byte{byte(i + 1)})
+			clients <- conn
+			i++
+		}
+	}()
+	if b := n.Receive(); b != 1 {
+		t.Fatalf("Expected to receive 1 from the first connection, instead got %d", b)
+	}
+	client := <-clients
+	<-clients
+	<-clients
+	for !n.SelectConn(2) {
+		time.Sleep(time.Millisecond)
+	}
+	if b := n.Receive(); b != 3 {
+		t.Fatalf("Expected to receive 3 from handle 2, instead got %d", b)
+	}
+	if !n.SelectConn(1) {
+		t.Fatal("Failed to select handle 1")
+	}
+	if b := n.Receive(); b != 2 {
+		t.Fatalf("Expected to receive 2 from handle 1, instead got %d", b)
+	}
+	n.CloseConn()
+	if n.SelectConn(1) || n.SelectConn(3) {
+		t.Fatal("Expected to fail selecting a closed or unknown handle")
+	}
+	// Without an active connection the lowest handle is used
+	n.QueueSend(9)
+	go n.Push()
+	reply := make([]byte, 1)
+	client.SetDeadline(time.Now().Add(time.Second))
+	if _, err := client.Read(reply); err != nil || reply[0] != 9 {
+		t.Fatalf("Expected the first client to receive 9, instead got %d (%v)", reply[0], err)
+	}
+	n.SetPort(1)
+	if n.state() != netStateIdle || n.SelectConn(0) {
+		t.Fatal("Expected setting the port to close all connections")
+	}
+}
+
+func TestNetworkPoll(t *testing.T) {
+	transport := NewMemoryTransport()
+	n := NewNetwork(WithTransport(transport))
+	n.SetPort(1)
+	if _, ok := n.Poll(); ok || n.state() != netStateListening || n.lastErr != NetErrTimeout {
+		t.Fatalf("Expected to start listening without receiving, instead got state %d and error %d", n.state(), n.lastErr)
+	}
+	client, err := transport.Dial("tcp4", "127.0.0.1:42001", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	go client.Write([]byte{0})
+	b, ok := n.Poll()
+	for !ok {
+		time.Sleep(time.Millisecond)
+		b, ok = n.Poll()
+	}
+	if b != 0 {
+		t.Fatalf("Expected to receive 0, instead got %d", b)
+	}
+	// Polling without data doesn't wait
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		if _, ok := n.Poll(); ok || n.state() != netStateConnected {
+			t.Fatalf("Expected to keep the connection without data, instead got state %d", n.state())
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+		t.Fatalf("Expected to poll without waiting, instead took %s", elapsed/100)
+	}
+	client.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for _, ok = n.Poll(); n.state() == netStateConnected && time.Now().Before(deadline); _, ok = n.Poll() {
+		time.Sleep(time.Millisecond)
+	}
+	if ok || n.state() != netStateListening || n.lastErr != NetErrClosed {
+		t.Fatalf("Expected to close the connection, instead got state %d and error %d", n.state(), n.lastErr)
+	}
+	// Polling datagrams doesn't wait either
+	n.SetDatagram(true)
+	start = time.Now()
+	for i := 0; i < 100; i++ {
+		if _, ok := n.Poll(); ok || n.state() != netStateListening || n.lastErr != NetErrTimeout {
+			t.Fatalf("Expected no datagram, instead got state %d and error %d", n.state(), n.lastErr)
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+		t.Fatalf("Expected to poll datagrams without waiting, instead took %s", elapsed/100)
+	}
+}
+
+func TestNetworkDeprecatedAddrs(t *testing.T) {
+	n := NewNetwork(WithPolicy(NetPolicyAllowlist))
+	n.SetPort(1)
+	if NetTargetAddr+n.port != n.targetAddress() || NetListenAddr+n.port != n.listenAddress() {
+		t.Fatalf("Expected the deprecated addresses to match %q and %q", n.targetAddress(), n.listenAddress())
+	}
+}
+
 func TestByteToPort(t *testing.T) {
 	for i := 0; i < 256; i++ {
-		actual := byteToPort(byte(i))
+		actual := byteToPort(NetBasePort, byte(i))
 		expected := fmt.Sprintf("%d", 42000+i)
 		if len(actual) != 5 || actual != expected {
 			t.Errorf("Got %q but expected %q", actual, expected)
@@ -45,6 +308,6 @@ func TestByteToPort(t *testing.T) {
 
 func BenchmarkByteToPort(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		byteToPort(byte(i))
+		byteToPort(NetBasePort, byte(i))
 	}
 }
@@ -0,0 +1,148 @@
+package interpreter
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+var (
+	ErrNetTLSInvalidCA = errors.New("no certificate found in the TLS CA file")
+)
+
+// How long a self-signed certificate is valid for
+const NetSelfSignedValidity = 24 * time.Hour
+
+// Wraps the dialed and accepted connections in TLS with the given configuration
+// Accepted connections require a certificate in the configuration
+// TLS is not used in datagram mode
+func WithTLS(config *tls.Config) NetworkOption {
+	return func(n *Network) {
+		n.tlsConfig = config
+	}
+}
+
+// Wraps a dialed connection in TLS if enabled
+// The server name defaults to the target host
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) clientTLS(conn net.Conn) net.Conn {
+	if n.tlsConfig == nil {
+		return conn
+	}
+	config := n.tlsConfig
+	if config.ServerName == "" {
+		config = config.Clone()
+		config.ServerName = n.targetAddr()
+	}
+	return tls.Client(conn, config)
+}
+
+// Wraps a listener in TLS if enabled
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) serverTLS(listener net.Listener) net.Listener {
+	if n.tlsConfig == nil {
+		return listener
+	}
+	return tls.NewListener(listener, n.tlsConfig)
+}
+
+// Waits for the TLS handshake of conn, which the reader completes within the timeout
+// Returns the handshake error, nil without TLS
+// NOTE: Requires the lock to be held by the current process, it's released while waiting
+func (n *Network) handshake(conn *netConn) error {
+	select {
+	case <-conn.ready:
+	default:
+		n.mu.Unlock()
+		<-conn.ready
+		n.mu.Lock()
+	}
+	return conn.handshakeErr
+}
+
+// Returns true if conn is a TLS connection whose handshake returned an error
+// A failed handshake can't be retried, even after a timeout
+func handshakeFailed(conn *netConn) bool {
+	select {
+	case <-conn.ready:
+		return conn.handshakeErr != nil
+	default:
+		return false
+	}
+}
+
+// Returns a TLS configuration with the certificate and key from the given
+// files, trusting the certificate authorities in caFile when connecting
+// Empty file names are skipped, the system certificate authorities are
+// trusted if caFile is empty
+func NewTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, ErrNetTLSInvalidCA
+		}
+	}
+	return config, nil
+}
+
+// Returns a TLS configuration with a new self-signed certificate for the
+// loopback hosts, which is also the only trusted certificate when connecting
+// Meant for tests
+func NewSelfSignedTLSConfig() (*tls.Config, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "ToyLanguage self-signed"},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(NetSelfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP(NetTargetHost), net.ParseIP(NetTargetHostIPv6)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}},
+		RootCAs:      roots,
+	}, nil
+}
@@ -0,0 +1,126 @@
+package interpreter
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+/*
+* Tests
+**/
+
+func TestNetworkTLS(t *testing.T) {
+	config, err := NewSelfSignedTLSConfig()
+	if err != nil {
+		t.Fatalf("Failed to generate a self-signed certificate: %v", err)
+	}
+	transport := NewMemoryTransport()
+	server := NewNetwork(WithTransport(transport), WithTLS(config))
+	client := NewNetwork(WithTransport(transport), WithTLS(config))
+	server.SetPort(1)
+	client.SetPort(1)
+	received := make(chan byte)
+	go func() {
+		received <- server.Receive()
+	}()
+	client.QueueSend(7)
+	for !client.Push() {
+		time.Sleep(time.Millisecond)
+	}
+	if b := <-received; b != 7 {
+		t.Fatalf("Expected the server to receive 7, instead got %d", b)
+	}
+	go func() {
+		received <- client.Receive()
+	}()
+	server.QueueSend(8)
+	if !server.Push() {
+		t.Fatal("Failed to reply")
+	}
+	if b := <-received; b != 8 {
+		t.Fatalf("Expected the client to receive 8, instead got %d", b)
+	}
+}
+
+func TestNetworkTLSHandshake(t *testing.T) {
+	config, err := NewSelfSignedTLSConfig()
+	if err != nil {
+		t.Fatalf("Failed to generate a self-signed certificate: %v", err)
+	}
+	other, err := NewSelfSignedTLSConfig()
+	if err != nil {
+		t.Fatalf("Failed to generate a self-signed certificate: %v", err)
+	}
+	// A plain server never answers the handshake
+	transport := NewMemoryTransport()
+	listener, err := transport.Listen("tcp4", "0.0.0.0:42001")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go listener.Accept()
+	client := NewNetwork(WithTransport(transport), WithTLS(config))
+	client.SetTimeout(1)
+	client.SetPort(1)
+	client.QueueSend(7)
+	if client.Push() || client.lastErr != NetErrTimeout || client.state() != netStateIdle {
+		t.Fatalf("Expected the handshake to time out, instead got error %d and state %d", client.lastErr, client.state())
+	}
+	listener.Close()
+	// The server certificate is not trusted
+	server := NewNetwork(WithTransport(transport), WithTLS(other))
+	server.SetTimeout(10)
+	server.SetPort(1)
+	go server.Receive()
+	client.SetTimeout(10)
+	deadline := time.Now().Add(5 * time.Second)
+	for client.Push() || client.lastErr != NetErrOther {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the handshake to fail, instead got error %d", client.lastErr)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// A handshake that didn't run yet hasn't failed
+	pipe, _ := net.Pipe()
+	if handshakeFailed(&netConn{Conn: tls.Client(pipe, config)}) {
+		t.Fatal("Expected a handshake that didn't run not to be failed")
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	config, err := NewSelfSignedTLSConfig()
+	if err != nil {
+		t.Fatalf("Failed to generate a self-signed certificate: %v", err)
+	}
+	key, err := x509.MarshalECPrivateKey(config.Certificates[0].PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatalf("Failed to encode the key: %v", err)
+	}
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: config.Certificates[0].Certificate[0]}), 0644)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key}), 0644)
+	loaded, err := NewTLSConfig(certFile, keyFile, certFile)
+	if err != nil {
+		t.Fatalf("Failed to load the certificate: %v", err)
+	}
+	if len(loaded.Certificates) != 1 || loaded.RootCAs == nil {
+		t.Fatal("Expected a certificate and a certificate authority")
+	}
+	if loaded, err := NewTLSConfig("", "", ""); err != nil || len(loaded.Certificates) != 0 || loaded.RootCAs != nil {
+		t.Fatalf("Expected an empty configuration, instead got %v", err)
+	}
+	if _, err := NewTLSConfig(certFile, "", ""); err == nil {
+		t.Fatal("Expected an error without a key")
+	}
+	if _, err := NewTLSConfig("", "", keyFile); err != ErrNetTLSInvalidCA {
+		t.Fatalf("Expected ErrNetTLSInvalidCA, instead got %v", err)
+	}
+}
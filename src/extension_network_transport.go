@@ -0,0 +1,355 @@
+package interpreter
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTransportAddrInUse = errors.New("address already in use")
+	ErrTransportRefused   = errors.New("connection refused")
+	ErrTransportNoPackets = errors.New("the transport does not support datagrams")
+)
+
+// Opens the listeners and connections used by the network extension
+type Transport interface {
+	// Listens for connections on the given address
+	Listen(network, address string) (net.Listener, error)
+	// Connects to the given address within the timeout
+	Dial(network, address string, timeout time.Duration) (net.Conn, error)
+}
+
+// A transport that can also receive datagrams, required by the datagram mode
+type PacketTransport interface {
+	Transport
+	// Listens for datagrams on the given address
+	ListenPacket(network, address string) (net.PacketConn, error)
+}
+
+// The default transport, uses the operating system network
+type systemTransport struct{}
+
+func (systemTransport) Listen(network, address string) (net.Listener, error) {
+	return net.Listen(network, address)
+}
+
+func (systemTransport) Dial(network, address string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout(network, address, timeout)
+}
+
+func (systemTransport) ListenPacket(network, address string) (net.PacketConn, error) {
+	return net.ListenPacket(network, address)
+}
+
+// Returns the transport using the operating system network
+func NewSystemTransport() PacketTransport {
+	return systemTransport{}
+}
+
+// An in-memory transport, connections are synchronous pipes (see net.Pipe)
+// Addresses are identified by their port, the host is ignored
+// Unix socket addresses ("unix") are identified by their path
+// Datagram networks ("udp", "udp4", "udp6") use buffered in-memory packets
+type MemoryTransport struct {
+	// Lock on the listeners
+	mu sync.Mutex
+	// Active listeners by port
+	listeners map[string]*memoryListener
+	// Active datagram sockets by port
+	packetConns map[int]*memoryPacketConn
+	// Last port assigned to a dialed datagram socket
+	ephemeralPort int
+}
+
+// Listens for connections on the port of the given address
+func (t *MemoryTransport) Listen(network, address string) (net.Listener, error) {
+	port, err := memoryPort(network, address)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, inUse := t.listeners[port]; inUse {
+		return nil, ErrTransportAddrInUse
+	}
+	listener := &memoryListener{
+		transport: t,
+		addr:      memoryAddr(address),
+		port:      port,
+		conns:     make(chan net.Conn, 16),
+		closed:    make(chan struct{}),
+	}
+	t.listeners[port] = listener
+	return listener, nil
+}
+
+// Connects to the listener on the port of the given address
+func (t *MemoryTransport) Dial(network, address string, timeout time.Duration) (net.Conn, error) {
+	if isDatagram(network) {
+		return t.dialPacket(address)
+	}
+	port, err := memoryPort(network, address)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	listener, ok := t.listeners[port]
+	t.mu.Unlock()
+	if !ok {
+		return nil, ErrTransportRefused
+	}
+	client, server := net.Pipe()
+	select {
+	case listener.conns <- server:
+		return client, nil
+	case <-listener.closed:
+	case <-time.After(timeout):
+	}
+	client.Close()
+	server.Close()
+	return nil, ErrTransportRefused
+}
+
+// Listens for datagrams on the port of the given address
+func (t *MemoryTransport) ListenPacket(network, address string) (net.PacketConn, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.bindPacket(addr.Port)
+}
+
+// Sends datagrams to the port of the given address from an ephemeral port
+func (t *MemoryTransport) dialPacket(address string) (net.Conn, error) {
+	remote, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for {
+		t.ephemeralPort++
+		if t.ephemeralPort > 65535 {
+			t.ephemeralPort = 1
+		}
+		if _, inUse := t.packetConns[t.ephemeralPort]; !inUse {
+			break
+		}
+	}
+	pc, err := t.bindPacket(t.ephemeralPort)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryDatagramConn{memoryPacketConn: pc, remote: remote}, nil
+}
+
+// Creates a datagram socket on the given port
+// NOTE: Requires the lock to be held by the current process
+func (t *MemoryTransport) bindPacket(port int) (*memoryPacketConn, error) {
+	if _, inUse := t.packetConns[port]; inUse {
+		return nil, ErrTransportAddrInUse
+	}
+	pc := &memoryPacketConn{
+		transport: t,
+		addr:      &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port},
+		packets:   make(chan memoryPacket, 64),
+		closed:    make(chan struct{}),
+	}
+	t.packetConns[port] = pc
+	return pc, nil
+}
+
+// Returns an empty in-memory transport
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{
+		listeners:     map[string]*memoryListener{},
+		packetConns:   map[int]*memoryPacketConn{},
+		ephemeralPort: 49151,
+	}
+}
+
+// A listener of the in-memory transport
+type memoryListener struct {
+	transport *MemoryTransport
+	addr      memoryAddr
+	port      string
+	// Pending connections
+	conns chan net.Conn
+	// Closed once the listener is closed
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *memoryListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *memoryListener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.closed)
+		l.transport.mu.Lock()
+		delete(l.transport.listeners, l.port)
+		l.transport.mu.Unlock()
+		// Refuse the pending connections
+		for {
+			select {
+			case conn := <-l.conns:
+				conn.Close()
+			default:
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func (l *memoryListener) Addr() net.Addr {
+	return l.addr
+}
+
+// The address of an in-memory listener
+type memoryAddr string
+
+func (a memoryAddr) Network() string {
+	return "memory"
+}
+
+func (a memoryAddr) String() string {
+	return string(a)
+}
+
+// A datagram received by an in-memory datagram socket
+type memoryPacket struct {
+	data []byte
+	from net.Addr
+}
+
+// A datagram socket of the in-memory transport
+// Datagrams sent to a missing or full socket are dropped
+type memoryPacketConn struct {
+	transport *MemoryTransport
+	addr      *net.UDPAddr
+	// Received datagrams
+	packets chan memoryPacket
+	// Closed once the socket is closed
+	closed    chan struct{}
+	closeOnce sync.Once
+	// Lock on the deadline
+	mu           sync.Mutex
+	readDeadline time.Time
+}
+
+func (c *memoryPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	c.mu.Lock()
+	deadline := c.readDeadline
+	c.mu.Unlock()
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case packet := <-c.packets:
+		return copy(b, packet.data), packet.from, nil
+	case <-c.closed:
+		return 0, nil, net.ErrClosed
+	case <-timeout:
+		return 0, nil, os.ErrDeadlineExceeded
+	}
+}
+
+func (c *memoryPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return 0, ErrTransportRefused
+	}
+	c.transport.mu.Lock()
+	target, ok := c.transport.packetConns[udpAddr.Port]
+	c.transport.mu.Unlock()
+	if ok {
+		packet := memoryPacket{data: append([]byte{}, b...), from: c.addr}
+		select {
+		case target.packets <- packet:
+		default:
+		}
+	}
+	return len(b), nil
+}
+
+func (c *memoryPacketConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.transport.mu.Lock()
+		delete(c.transport.packetConns, c.addr.Port)
+		c.transport.mu.Unlock()
+	})
+	return nil
+}
+
+func (c *memoryPacketConn) LocalAddr() net.Addr {
+	return c.addr
+}
+
+func (c *memoryPacketConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *memoryPacketConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	return nil
+}
+
+func (c *memoryPacketConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// A datagram socket of the in-memory transport sending to a fixed address
+type memoryDatagramConn struct {
+	*memoryPacketConn
+	remote net.Addr
+}
+
+func (c *memoryDatagramConn) Read(b []byte) (int, error) {
+	n, _, err := c.ReadFrom(b)
+	return n, err
+}
+
+func (c *memoryDatagramConn) Write(b []byte) (int, error) {
+	return c.WriteTo(b, c.remote)
+}
+
+func (c *memoryDatagramConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// Returns the key of a listener of the in-memory transport: the port, or the path of a Unix socket
+func memoryPort(network, address string) (string, error) {
+	if network == "unix" {
+		return address, nil
+	}
+	_, port, err := net.SplitHostPort(address)
+	return port, err
+}
+
+// Returns true if network is a datagram network
+func isDatagram(network string) bool {
+	return network == "udp" || network == "udp4" || network == "udp6"
+}
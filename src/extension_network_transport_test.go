@@ -0,0 +1,132 @@
+package interpreter
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+* Tests
+**/
+
+func TestMemoryTransport(t *testing.T) {
+	transport := NewMemoryTransport()
+	if _, err := transport.Dial("tcp4", "127.0.0.1:42001", time.Second); err != ErrTransportRefused {
+		t.Fatalf("Expected ErrTransportRefused without a listener, instead got %v", err)
+	}
+	listener, err := transport.Listen("tcp4", "0.0.0.0:42001")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	if _, err := transport.Listen("tcp4", "0.0.0.0:42001"); err != ErrTransportAddrInUse {
+		t.Fatalf("Expected ErrTransportAddrInUse, instead got %v", err)
+	}
+	if listener.Addr().String() != "0.0.0.0:42001" {
+		t.Fatalf("Unexpected listener address %q", listener.Addr().String())
+	}
+	client, err := transport.Dial("tcp4", "127.0.0.1:42001", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	server, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+	go client.Write([]byte("ping"))
+	received := make([]byte, 4)
+	server.SetDeadline(time.Now().Add(time.Second))
+	if _, err := server.Read(received); err != nil || string(received) != "ping" {
+		t.Fatalf("Expected to receive %q, instead got %q (%v)", "ping", received, err)
+	}
+	client.Close()
+	server.Close()
+	listener.Close()
+	if _, err := listener.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("Expected net.ErrClosed after closing, instead got %v", err)
+	}
+	if _, err := transport.Listen("tcp4", "0.0.0.0:42001"); err != nil {
+		t.Fatalf("Expected to listen again after closing, instead got %v", err)
+	}
+}
+
+func TestNetworkPrograms(t *testing.T) {
+	transport := NewMemoryTransport()
+	// The server receives a byte, prints it, and replies with the next byte
+	server, err := NewProgram(strings.NewReader(`tl:net +@ ?.+^;`),
+		WithNetwork(NewNetwork(WithTransport(transport))))
+	if err != nil {
+		t.Fatalf("Failed to load server program: %v", err)
+	}
+	serverOutput := bytes.NewBuffer(make([]byte, 0, 1))
+	server.IOWriter = serverOutput
+	// The client sends a byte from its input until successful, then prints the reply
+	client, err := NewProgram(strings.NewReader(`tl:net +@ >,^[;] ?.`),
+		WithNetwork(NewNetwork(WithTransport(transport))))
+	if err != nil {
+		t.Fatalf("Failed to load client program: %v", err)
+	}
+	client.IOReader = strings.NewReader("A")
+	clientOutput := bytes.NewBuffer(make([]byte, 0, 1))
+	client.IOWriter = clientOutput
+	// Run
+	serverErr := make(chan error)
+	go func() {
+		serverErr <- server.Run(100)
+	}()
+	if err := client.Run(1000000); err != nil {
+		t.Fatalf("Expected no error from the client, instead got %v", err)
+	}
+	if err := <-serverErr; err != nil {
+		t.Fatalf("Expected no error from the server, instead got %v", err)
+	}
+	if serverOutput.String() != "A" {
+		t.Fatalf("Expected the server to receive %q, instead got %q", "A", serverOutput.String())
+	}
+	if clientOutput.String() != "B" {
+		t.Fatalf("Expected the client to receive %q, instead got %q", "B", clientOutput.String())
+	}
+}
+
+func TestNetworkPollProgram(t *testing.T) {
+	transport := NewMemoryTransport()
+	// The server polls until a byte is received, then prints it
+	server, err := NewProgram(strings.NewReader(`tl:net +@ >+[<|>-]<.`),
+		WithNetwork(NewNetwork(WithTransport(transport))))
+	if err != nil {
+		t.Fatalf("Failed to load server program: %v", err)
+	}
+	serverOutput := bytes.NewBuffer(make([]byte, 0, 1))
+	server.IOWriter = serverOutput
+	// The client sends a byte from its input until successful
+	client, err := NewProgram(strings.NewReader(`tl:net +@ >,^[;]`),
+		WithNetwork(NewNetwork(WithTransport(transport))))
+	if err != nil {
+		t.Fatalf("Failed to load client program: %v", err)
+	}
+	client.IOReader = strings.NewReader("A")
+	// Start the server and wait for it to listen, polling never waits for a connection
+	serverErr := make(chan error)
+	go func() {
+		serverErr <- server.Run(math.MaxInt32)
+	}()
+	for listening := false; !listening; {
+		transport.mu.Lock()
+		_, listening = transport.listeners["42001"]
+		transport.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	if err := client.Run(1000); err != nil {
+		t.Fatalf("Expected no error from the client, instead got %v", err)
+	}
+	if err := <-serverErr; err != nil {
+		t.Fatalf("Expected no error from the server, instead got %v", err)
+	}
+	if serverOutput.String() != "A" {
+		t.Fatalf("Expected the server to receive %q, instead got %q", "A", serverOutput.String())
+	}
+}
@@ -0,0 +1,240 @@
+package interpreter
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+)
+
+const (
+	// The largest datagram that can be received
+	NetMaxDatagram = 65535
+)
+
+// A datagram socket reading in the background
+type netPacketConn struct {
+	net.PacketConn
+	// Datagrams received in the background, closed once the reader stopped
+	datagrams chan netDatagram
+	// Closed with the socket, stops the reader
+	done chan struct{}
+	// Closed once the reader stopped using its buffer
+	stopped chan struct{}
+}
+
+// A datagram received by a socket and the error that ended the read, if any
+type netDatagram struct {
+	data []byte
+	from net.Addr
+	err  error
+}
+
+// Returns a socket reading from pc in the background into the given buffer
+// The buffer must not be used by another socket until this one is closed
+func newNetPacketConn(pc net.PacketConn, buffer []byte) *netPacketConn {
+	conn := &netPacketConn{
+		PacketConn: pc,
+		datagrams:  make(chan netDatagram, NetReadChunks),
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	go conn.readLoop(buffer)
+	return conn
+}
+
+// Reads datagrams until the socket fails, keeping a copy of each one
+func (c *netPacketConn) readLoop(buffer []byte) {
+	defer close(c.stopped)
+	defer close(c.datagrams)
+	for {
+		nRead, from, err := c.PacketConn.ReadFrom(buffer)
+		datagram := netDatagram{data: append([]byte{}, buffer[:nRead]...), from: from, err: err}
+		select {
+		case c.datagrams <- datagram:
+		case <-c.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Closes the socket and waits for the reader to release its buffer
+func (c *netPacketConn) Close() error {
+	close(c.done)
+	err := c.PacketConn.Close()
+	<-c.stopped
+	return err
+}
+
+// Returns the network name for the transport
+func (n *Network) networkName() string {
+	if n.unixDir != "" && n.datagram {
+		return "unixgram"
+	}
+	if n.unixDir != "" {
+		return "unix"
+	}
+	if !n.datagram {
+		return n.family
+	}
+	if n.family == "tcp6" {
+		return "udp6"
+	}
+	return "udp4"
+}
+
+// Switches between stream (TCP) and datagram (UDP) mode, resetting the network
+func (n *Network) SetDatagram(datagram bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.datagram == datagram {
+		return
+	}
+	n.reset(true)
+	n.datagram = datagram
+}
+
+// Sends the send queue as one datagram
+// Returns true if successful, false otherwise
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) pushDatagram() bool {
+	address := n.targetAddress()
+	if n.checkPolicy(n.targetAddr()) != nil {
+		n.audit("deny", address, 0)
+		n.lastErr = NetErrDenied
+		return false
+	}
+	conn, err := n.transport.Dial(n.networkName(), address, n.timeout)
+	if err != nil {
+		n.audit("connect-error", address, 0)
+		n.fail(err, NetErrRefused)
+		return false
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(n.timeout))
+	nSent, err := conn.Write(n.sendCache)
+	n.audit("send", address, nSent)
+	if nSent != len(n.sendCache) || err != nil {
+		n.fail(err, errorCode(err))
+		return false
+	}
+	n.sendCache = []byte{}
+	return true
+}
+
+// Opens the datagram socket, the transport must be a PacketTransport
+// Returns true if successful, false otherwise
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) listenPacket() bool {
+	address := n.listenAddress()
+	if n.checkPolicy(n.listenAddr()) != nil {
+		n.audit("deny", address, 0)
+		n.lastErr = NetErrDenied
+		return false
+	}
+	transport, ok := n.transport.(PacketTransport)
+	if !ok {
+		n.lastErr = NetErrRefused
+		return false
+	}
+	packetConn, err := transport.ListenPacket(n.networkName(), address)
+	if err != nil {
+		n.fail(err, NetErrRefused)
+		return false
+	}
+	n.audit("listen", address, 0)
+	if n.datagramBuffer == nil {
+		n.datagramBuffer = make([]byte, NetMaxDatagram)
+	}
+	n.packetConn = newNetPacketConn(packetConn, n.datagramBuffer)
+	return true
+}
+
+// Reads the next byte of the last datagram, or waits up to the given timeout (not at all if 0) for a new datagram
+// Returns the byte if successful, false otherwise
+// NOTE: Requires the lock to be held by the current process, it's released while waiting
+func (n *Network) receiveDatagram(timeout time.Duration) (byte, bool) {
+	if len(n.recvCache) == 0 {
+		if n.packetConn == nil && !n.listenPacket() {
+			return 0, false
+		}
+		if !n.waitDatagram(n.packetConn, timeout) {
+			return 0, false
+		}
+	}
+	b := n.recvCache[0]
+	n.recvCache = n.recvCache[1:]
+	return b, true
+}
+
+// Waits up to timeout (not at all if 0) for the next datagram of conn and caches its bytes
+// Returns true if successful, false otherwise
+// NOTE: Requires the lock to be held by the current process, it's released while waiting
+func (n *Network) waitDatagram(conn *netPacketConn, timeout time.Duration) bool {
+	var datagram netDatagram
+	received, ok := false, false
+	if timeout <= 0 {
+		select {
+		case datagram, ok = <-conn.datagrams:
+			received = true
+		default:
+		}
+	} else {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		n.mu.Unlock()
+		select {
+		case datagram, ok = <-conn.datagrams:
+			received = true
+		case <-timer.C:
+		}
+		n.mu.Lock()
+	}
+	switch {
+	case n.packetConn != conn:
+		// The socket was closed while waiting
+		n.lastErr = NetErrClosed
+		return false
+	case !received:
+		n.lastErr = NetErrTimeout
+		return false
+	case !ok || datagram.err != nil:
+		// The socket failed, listen again on the next receive
+		n.fail(datagram.err, errorCode(datagram.err))
+		n.stopListening()
+		return false
+	case len(datagram.data) == 0:
+		n.lastErr = NetErrClosed
+		return false
+	}
+	n.audit("receive", datagram.from.String(), len(datagram.data))
+	n.source = datagram.from
+	n.recvCache = append(n.recvCache, datagram.data...)
+	return true
+}
+
+// Returns the address the last datagram came from: the IP (4 bytes, or
+// 16 bytes with IPv6) followed by the port (2 bytes, big-endian)
+// All bytes are 0 if no datagram was received
+func (n *Network) Source() []byte {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ipLen := net.IPv4len
+	if n.family == "tcp6" {
+		ipLen = net.IPv6len
+	}
+	source := make([]byte, ipLen+2)
+	udpAddr, ok := n.source.(*net.UDPAddr)
+	if !ok {
+		return source
+	}
+	ip := udpAddr.IP.To16()
+	if ipLen == net.IPv4len {
+		ip = udpAddr.IP.To4()
+	}
+	copy(source, ip)
+	binary.BigEndian.PutUint16(source[ipLen:], uint16(udpAddr.Port))
+	return source
+}
@@ -0,0 +1,108 @@
+package interpreter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+* Tests
+**/
+
+func TestNetworkDatagram(t *testing.T) {
+	transport := NewMemoryTransport()
+	// The server receives a datagram, tries to read past its end, and saves the source address
+	server, err := NewProgram(strings.NewReader(`tl:udp +*@ ?>?>?> `+"`"),
+		WithNetwork(NewNetwork(WithTransport(transport))))
+	if err != nil {
+		t.Fatalf("Failed to load server program: %v", err)
+	}
+	if !server.HasExtensions(ExtNet) || !server.Network.datagram {
+		t.Fatal("Expected the udp extension to enable the network extension in datagram mode")
+	}
+	// The client sends its input as one datagram
+	client, err := NewProgram(strings.NewReader(`tl:udp +@ >,^,^;`),
+		WithNetwork(NewNetwork(WithTransport(transport))))
+	if err != nil {
+		t.Fatalf("Failed to load client program: %v", err)
+	}
+	client.IOReader = strings.NewReader("Hi")
+	// Start the server and wait for it to listen
+	serverErr := make(chan error)
+	go func() {
+		serverErr <- server.Run(100)
+	}()
+	for listening := false; !listening; {
+		transport.mu.Lock()
+		_, listening = transport.packetConns[42001]
+		transport.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	if err := client.Run(100); err != nil {
+		t.Fatalf("Expected no error from the client, instead got %v", err)
+	}
+	if client.Memory.Get() != 0 {
+		t.Fatalf("Expected the client to send successfully, instead got %d", client.Memory.Get())
+	}
+	if err := <-serverErr; err != nil {
+		t.Fatalf("Expected no error from the server, instead got %v", err)
+	}
+	// The third receive times out at the end of the datagram
+	expectedMem := []byte{'H', 'i', 0, 127, 0, 0, 1, 0xc0}
+	if !bytes.Equal(server.Memory.Bytes(), expectedMem) {
+		t.Fatalf("Expected %+d, but memory is %+d", expectedMem, server.Memory.Bytes())
+	}
+}
+
+func TestNetworkDatagramSource(t *testing.T) {
+	n := NewNetwork(WithTransport(NewMemoryTransport()), WithIPv6())
+	if !bytes.Equal(n.Source(), make([]byte, 18)) {
+		t.Fatalf("Expected an empty IPv6 source, instead got %+d", n.Source())
+	}
+	n = NewNetwork(WithTransport(NewMemoryTransport()))
+	if !bytes.Equal(n.Source(), make([]byte, 6)) {
+		t.Fatalf("Expected an empty IPv4 source, instead got %+d", n.Source())
+	}
+	n.SetDatagram(true)
+	if n.networkName() != "udp4" {
+		t.Fatalf("Expected udp4 in datagram mode, instead got %q", n.networkName())
+	}
+}
+
+// A transport without datagrams
+type streamTransport struct {
+	Transport
+}
+
+func TestNetworkDatagramTransport(t *testing.T) {
+	memory := NewMemoryTransport()
+	n := NewNetwork(WithTransport(streamTransport{memory}))
+	n.SetTimeout(1)
+	n.SetPort(1)
+	// Streams still work
+	if n.Receive(); n.state() != netStateListening {
+		t.Fatalf("Expected to listen for connections, instead got state %d", n.state())
+	}
+	// Datagrams are refused
+	n.SetDatagram(true)
+	if n.Receive(); n.state() != netStateIdle || n.lastErr != NetErrRefused {
+		t.Fatalf("Expected datagrams to be refused, instead got state %d and error %d", n.state(), n.lastErr)
+	}
+	recording := NewRecordingTransport(streamTransport{memory}, &bytes.Buffer{})
+	if _, err := recording.ListenPacket("udp4", "127.0.0.1:42001"); err != ErrTransportNoPackets {
+		t.Fatalf("Expected the recording to refuse datagrams, instead got %v", err)
+	}
+	// Every datagram socket reuses the same buffer
+	n = NewNetwork(WithTransport(memory))
+	n.SetDatagram(true)
+	n.SetTimeout(1)
+	n.Receive()
+	buffer := n.datagramBuffer
+	n.SetPort(2)
+	n.Receive()
+	if len(buffer) != NetMaxDatagram || &n.datagramBuffer[0] != &buffer[0] {
+		t.Fatal("Expected the datagram sockets to share one buffer")
+	}
+}
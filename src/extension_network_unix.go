@@ -0,0 +1,43 @@
+package interpreter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// How long to wait when checking if a socket file is stale
+const NetStaleSocketTimeout = 100 * time.Millisecond
+
+// Uses Unix domain sockets in dir instead of TCP (or UDP in datagram mode)
+// The socket for a port is "<dir>/<port>.sock" (ex: "/tmp/tl/42001.sock")
+// Unix sockets are always local, so the allowed hosts are not checked
+func WithUnixSockets(dir string) NetworkOption {
+	return func(n *Network) {
+		n.unixDir = dir
+	}
+}
+
+// Returns the socket path for the current port
+func (n *Network) socketPath() string {
+	return filepath.Join(n.unixDir, n.port+".sock")
+}
+
+// Removes the socket file at path if nothing is listening on it anymore,
+// checked by connecting through the transport
+// Returns true if it was removed
+// NOTE: Requires the lock to be held by the current process
+func (n *Network) removeStaleSocket(path string) bool {
+	conn, err := n.transport.Dial("unix", path, NetStaleSocketTimeout)
+	if err == nil {
+		conn.Close()
+		return false
+	}
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return false
+	}
+	err = os.Remove(path)
+	return err == nil || errors.Is(err, os.ErrNotExist)
+}
@@ -0,0 +1,105 @@
+package interpreter
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+* Tests
+**/
+
+func TestNetworkUnixSockets(t *testing.T) {
+	dir := t.TempDir()
+	server := NewNetwork(WithUnixSockets(dir))
+	client := NewNetwork(WithUnixSockets(dir))
+	server.SetPort(1)
+	client.SetPort(1)
+	received := make(chan byte)
+	go func() {
+		received <- server.Receive()
+	}()
+	client.QueueSend(7)
+	for !client.Push() {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "42001.sock")); err != nil {
+		t.Fatalf("Expected a socket file, instead got %v", err)
+	}
+	if b := <-received; b != 7 {
+		t.Fatalf("Expected the server to receive 7, instead got %d", b)
+	}
+	server.SetPort(1)
+	client.SetPort(1)
+	if _, err := os.Stat(filepath.Join(dir, "42001.sock")); !os.IsNotExist(err) {
+		t.Fatalf("Expected the socket file to be removed, instead got %v", err)
+	}
+}
+
+func TestNetworkUnixAddresses(t *testing.T) {
+	// Dial, listen, then dial to check for a stale socket after the refused listen
+	socket := []string{"unix /run/tl/42001.sock", "unix /run/tl/42001.sock", "unix /run/tl/42001.sock"}
+	testCases := []struct {
+		opts      []NetworkOption
+		addresses []string
+	}{
+		{[]NetworkOption{}, socket},
+		{[]NetworkOption{WithPolicy(NetPolicyLoopback), WithTargetHost("10.0.0.2")}, socket},
+		{[]NetworkOption{WithPolicy(NetPolicyDisabled)}, []string{}},
+		{[]NetworkOption{WithAllowedPorts(42002)}, []string{}},
+	}
+	for _, test := range testCases {
+		transport := &addressTransport{}
+		n := NewNetwork(append(test.opts, WithTransport(transport), WithUnixSockets("/run/tl"))...)
+		if err := n.Validate(); err != nil {
+			t.Fatalf("Expected a valid network, instead got %v", err)
+		}
+		n.SetTimeout(1)
+		n.SetPort(1)
+		n.QueueSend(1)
+		n.Push()
+		n.Receive()
+		if strings.Join(transport.addresses, ",") != strings.Join(test.addresses, ",") {
+			t.Fatalf("Expected addresses %q, instead got %q", test.addresses, transport.addresses)
+		}
+	}
+	n := NewNetwork(WithUnixSockets("/run/tl"))
+	n.SetDatagram(true)
+	if n.networkName() != "unixgram" {
+		t.Fatalf("Expected unixgram in datagram mode, instead got %q", n.networkName())
+	}
+}
+
+func TestRemoveStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "42000.sock")
+	n := NewNetwork()
+	if n.removeStaleSocket(path) {
+		t.Fatal("Expected nothing to remove without a socket file")
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	if n.removeStaleSocket(path) {
+		t.Fatal("Expected to keep the socket of a listener")
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	if !n.removeStaleSocket(path) {
+		t.Fatal("Expected to remove a stale socket")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Expected the socket file to be removed, instead got %v", err)
+	}
+	// The check connects through the transport
+	transport := &addressTransport{}
+	n = NewNetwork(WithTransport(transport))
+	n.removeStaleSocket(path)
+	if len(transport.addresses) != 1 || transport.addresses[0] != "unix "+path {
+		t.Fatalf("Expected to dial %q through the transport, instead got %q", path, transport.addresses)
+	}
+}
@@ -0,0 +1,35 @@
+package interpreter
+
+import (
+	"math/rand"
+	"time"
+)
+
+// The random extension, a seeded pseudo-random byte generator
+type Random struct {
+	rng *rand.Rand
+}
+
+// Seeds the generator
+func (r *Random) SetSeed(seed int64) {
+	r.rng = rand.New(rand.NewSource(seed))
+}
+
+// Returns a pseudo-random byte
+func (r *Random) Byte() byte {
+	return byte(r.rng.Intn(256))
+}
+
+// Seeds the random extension generator, making runs reproducible
+func WithRandomSeed(seed int64) ProgramOption {
+	return func(p *Program) {
+		p.Random.SetSeed(seed)
+	}
+}
+
+// Returns a generator seeded with the current time
+func NewRandom() *Random {
+	r := &Random{}
+	r.SetSeed(time.Now().UnixNano())
+	return r
+}
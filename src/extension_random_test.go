@@ -0,0 +1,68 @@
+package interpreter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+/*
+* Tests
+**/
+
+func TestRandom(t *testing.T) {
+	t.Run("Seed", func(t *testing.T) {
+		outputs := make([][]byte, 2)
+		for i := range outputs {
+			p, err := NewProgram(strings.NewReader(`tl:rnd &>&>&>&`), WithRandomSeed(42))
+			if err != nil {
+				t.Fatalf("Failed to load test program: %v", err)
+			}
+			if err = p.Run(100); err != nil {
+				t.Fatalf("Expected no error on Run, instead got %v", err)
+			}
+			outputs[i] = p.Memory.Bytes()
+		}
+		if len(outputs[0]) == 0 || !bytes.Equal(outputs[0], outputs[1]) {
+			t.Fatalf("Expected the same seed to be reproducible, got %+d and %+d", outputs[0], outputs[1])
+		}
+	})
+	t.Run("SeedFromCell", func(t *testing.T) {
+		outputs := make([][]byte, 2)
+		for i := range outputs {
+			// Each run starts with a different seed, but reseeds from a cell
+			p, err := NewProgram(strings.NewReader(`tl:rnd +++"&>&`), WithRandomSeed(int64(i)))
+			if err != nil {
+				t.Fatalf("Failed to load test program: %v", err)
+			}
+			if err = p.Run(100); err != nil {
+				t.Fatalf("Expected no error on Run, instead got %v", err)
+			}
+			outputs[i] = p.Memory.Bytes()
+		}
+		if !bytes.Equal(outputs[0], outputs[1]) {
+			t.Fatalf("Expected the seed from the cell to be reproducible, got %+d and %+d", outputs[0], outputs[1])
+		}
+	})
+	t.Run("Distribution", func(t *testing.T) {
+		r := NewRandom()
+		seen := map[byte]bool{}
+		for i := 0; i < 10000; i++ {
+			seen[r.Byte()] = true
+		}
+		if len(seen) != 256 {
+			t.Fatalf("Expected all 256 values to be generated, instead got %d", len(seen))
+		}
+	})
+}
+
+/*
+* Benchmarks
+**/
+
+func BenchmarkRandom(b *testing.B) {
+	r := NewRandom()
+	for i := b.N - 1; i >= 0; i-- {
+		r.Byte()
+	}
+}
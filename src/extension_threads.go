@@ -0,0 +1,151 @@
+package interpreter
+
+import "math/rand"
+
+// A thread of execution, it shares the memory cells with all other threads
+type thread struct {
+	// The program running this thread (nil for the main thread)
+	program *Program
+	// The thread that forked this one (nil for the main thread)
+	parent *thread
+	// Number of running threads forked by this one
+	children int
+}
+
+// The threads extension, a scheduler running one instruction per thread in turns
+type Threads struct {
+	// Running threads, the first one is the main thread
+	threads []*thread
+	// The main thread
+	main *thread
+	// Threads yet to run in the current round
+	round []*thread
+	// Scheduler seed, if 0 threads run in the order they were forked
+	seed int64
+	// Shuffles the order of each round (nil for plain round-robin)
+	rng *rand.Rand
+}
+
+// Sets the scheduler seed and resets the scheduler
+// A seed of 0 runs threads in the order they were forked, any other
+// value shuffles the order within each round in a reproducible way
+func (t *Threads) SetSeed(seed int64) {
+	t.seed = seed
+	t.Reset()
+}
+
+// Terminates all the forked threads and resets the scheduler
+func (t *Threads) Reset() {
+	t.main = &thread{}
+	t.threads = []*thread{t.main}
+	t.round = nil
+	t.rng = nil
+	if t.seed != 0 {
+		t.rng = rand.New(rand.NewSource(t.seed))
+	}
+}
+
+// Terminates all the threads, including the main one
+func (t *Threads) stop() {
+	t.threads = []*thread{}
+	t.round = nil
+}
+
+// Returns the number of running threads
+func (t *Threads) Count() int {
+	return len(t.threads)
+}
+
+// Returns the thread backing the given program
+func (t *Threads) lookup(p *Program) *thread {
+	if p.thread == nil {
+		return t.main
+	}
+	return p.thread
+}
+
+// Forks the given program into a new thread
+// As in Brainfork the parent's current cell is set to 0, while the child
+// starts from the next cell which is set to 1
+func (t *Threads) fork(p *Program) error {
+	child := *p
+	child.Instructions = p.Instructions.fork()
+	child.Memory = p.Memory.fork()
+	if err := child.Memory.Next(); err != nil {
+		return err
+	}
+	p.Memory.Set(0)
+	child.Memory.Set(1)
+	parent := t.lookup(p)
+	child.thread = &thread{
+		program: &child,
+		parent:  parent,
+	}
+	parent.children++
+	t.threads = append(t.threads, child.thread)
+	return nil
+}
+
+// Returns true if the given program has forked threads still running
+func (t *Threads) isWaiting(p *Program) bool {
+	return t.lookup(p).children > 0
+}
+
+// Removes a terminated thread
+func (t *Threads) exit(th *thread) {
+	if th.parent != nil {
+		th.parent.children--
+	}
+	for i := range t.threads {
+		if t.threads[i] == th {
+			t.threads = append(t.threads[:i], t.threads[i+1:]...)
+			return
+		}
+	}
+}
+
+// Runs the next instruction of the next scheduled thread
+// main is the program running the main thread
+// Returns ErrProgramDone once all threads terminated
+func (t *Threads) runNext(main *Program) error {
+	if len(t.round) == 0 {
+		if len(t.threads) == 0 {
+			return ErrProgramDone
+		}
+		t.round = append(make([]*thread, 0, len(t.threads)), t.threads...)
+		if t.rng != nil {
+			t.rng.Shuffle(len(t.round), func(i, j int) {
+				t.round[i], t.round[j] = t.round[j], t.round[i]
+			})
+		}
+	}
+	th := t.round[0]
+	t.round = t.round[1:]
+	p := th.program
+	if p == nil {
+		p = main
+	}
+	err := p.runInstruction()
+	if err == ErrProgramDone {
+		t.exit(th)
+		if len(t.threads) == 0 {
+			return ErrProgramDone
+		}
+		return nil
+	}
+	return err
+}
+
+// Sets the seed used by the threads scheduler
+func WithThreadSeed(seed int64) ProgramOption {
+	return func(p *Program) {
+		p.Threads.SetSeed(seed)
+	}
+}
+
+// Returns a scheduler running only the main thread
+func NewThreads() *Threads {
+	t := &Threads{}
+	t.Reset()
+	return t
+}
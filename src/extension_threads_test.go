@@ -0,0 +1,136 @@
+package interpreter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+/*
+* Tests
+**/
+
+func TestThreads(t *testing.T) {
+	t.Run("Fork", func(t *testing.T) {
+		p, err := NewProgram(strings.NewReader(`tl:thr +Y+++W`))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		t.Logf("Parsed %s", string(p.Instructions.instruction))
+		if err = p.Run(100); err != nil {
+			t.Fatalf("Expected no error on Run, instead got %v", err)
+		}
+		actualMem := p.Memory.Bytes()
+		expectedMem := []byte{3, 4}
+		if !bytes.Equal(actualMem, expectedMem) {
+			t.Fatalf("Expected %+d, but memory is %+d", expectedMem, actualMem)
+		}
+		if p.Threads.Count() != 0 {
+			t.Fatalf("Expected all threads to terminate, instead %d are running", p.Threads.Count())
+		}
+	})
+	t.Run("RoundRobin", func(t *testing.T) {
+		p, err := NewProgram(strings.NewReader(`tl:thr >Y[.>]<.`))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		outputBuffer := bytes.NewBuffer(make([]byte, 0, 4))
+		p.IOWriter = outputBuffer
+		if err = p.Run(100); err != nil {
+			t.Fatalf("Expected no error on Run, instead got %v", err)
+		}
+		// The main thread skips the loop and prints 0 while the child prints 1 twice
+		expectedOutput := []byte{1, 0, 1}
+		if !bytes.Equal(outputBuffer.Bytes(), expectedOutput) {
+			t.Fatalf("Expected %+d, instead got %+d", expectedOutput, outputBuffer.Bytes())
+		}
+	})
+	t.Run("Wait", func(t *testing.T) {
+		p, err := NewProgram(strings.NewReader(`tl:thr Y[>+++++<[-]]W>>.`))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		outputBuffer := bytes.NewBuffer(make([]byte, 0, 1))
+		p.IOWriter = outputBuffer
+		if err = p.Run(100); err != nil {
+			t.Fatalf("Expected no error on Run, instead got %v", err)
+		}
+		// The child prints an empty cell before the main thread prints 5
+		if !bytes.Equal(outputBuffer.Bytes(), []byte{0, 5}) {
+			t.Fatalf("Expected the main thread to wait for the child, instead got %+d", outputBuffer.Bytes())
+		}
+	})
+	t.Run("Seed", func(t *testing.T) {
+		outputs := make([][]byte, 2)
+		for i := range outputs {
+			p, err := NewProgram(strings.NewReader(`tl:thr YYY.+.`), WithThreadSeed(42))
+			if err != nil {
+				t.Fatalf("Failed to load test program: %v", err)
+			}
+			outputBuffer := bytes.NewBuffer(make([]byte, 0, 16))
+			p.IOWriter = outputBuffer
+			if err = p.Run(1000); err != nil {
+				t.Fatalf("Expected no error on Run, instead got %v", err)
+			}
+			outputs[i] = outputBuffer.Bytes()
+		}
+		if !bytes.Equal(outputs[0], outputs[1]) {
+			t.Fatalf("Expected the same seed to be reproducible, got %+d and %+d", outputs[0], outputs[1])
+		}
+	})
+	t.Run("OutOfBoundary", func(t *testing.T) {
+		p, err := NewProgram(strings.NewReader(`tl:thr Y`))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		p.Memory.p = MemSize - 1
+		if err = p.Run(10); err != ErrMemOutOfBoundary {
+			t.Fatalf("Expected ErrMemOutOfBoundary, instead got %v", err)
+		}
+		if p.Threads.Count() != 1 {
+			t.Fatalf("Expected no thread to be forked, instead %d are running", p.Threads.Count())
+		}
+	})
+	t.Run("Reset", func(t *testing.T) {
+		p, err := NewProgram(strings.NewReader(`tl:thr Y+[]`))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		if err = p.Run(10); err != ErrExecutionLimit {
+			t.Fatalf("Expected ErrExecutionLimit, instead got %v", err)
+		}
+		if p.Threads.Count() != 2 {
+			t.Fatalf("Expected 2 running threads, instead got %d", p.Threads.Count())
+		}
+		p.Reset()
+		if p.Threads.Count() != 1 {
+			t.Fatalf("Expected only the main thread after reset, instead got %d", p.Threads.Count())
+		}
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		p, err := NewProgram(strings.NewReader(`Y+W`))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		if !bytes.Equal(p.GetInstructions(), []byte{'+'}) {
+			t.Fatalf("Expected thread instructions to be ignored, instead got %q", p.GetInstructions())
+		}
+	})
+}
+
+/*
+* Benchmarks
+**/
+
+func BenchmarkThreads(b *testing.B) {
+	p, err := NewProgram(strings.NewReader(`tl:thr Y+[]`))
+	if err != nil {
+		b.Fatalf("Failed to load test program: %v", err)
+	}
+	b.ResetTimer()
+	err = p.Run(b.N)
+	b.StopTimer()
+	if err != ErrExecutionLimit && err != nil {
+		b.Fatalf("Expected hitting executing limit, instead got %v", err)
+	}
+}
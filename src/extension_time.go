@@ -0,0 +1,73 @@
+package interpreter
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+const (
+	// The resolution of the time extension
+	TimTick = 10 * time.Millisecond
+	// The number of bytes used to store the time
+	TimBytes = 4
+)
+
+// A source of monotonic time
+type Clock interface {
+	// Returns the time elapsed since the clock started
+	Elapsed() time.Duration
+	// Pauses the program for the given duration
+	Sleep(d time.Duration)
+}
+
+// The system monotonic clock
+type systemClock struct {
+	start time.Time
+}
+
+func (c systemClock) Elapsed() time.Duration {
+	return time.Since(c.start)
+}
+
+func (c systemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// A clock that only moves when asked to, useful for deterministic tests
+type FakeClock struct {
+	elapsed time.Duration
+}
+
+// Returns the time elapsed since the clock was created
+func (c *FakeClock) Elapsed() time.Duration {
+	return c.elapsed
+}
+
+// Advances the clock by d without pausing
+func (c *FakeClock) Sleep(d time.Duration) {
+	c.elapsed += d
+}
+
+// Returns a clock starting now
+func NewSystemClock() Clock {
+	return systemClock{start: time.Now()}
+}
+
+// Replaces the clock of the time extension
+func WithClock(c Clock) ProgramOption {
+	return func(p *Program) {
+		p.Clock = c
+	}
+}
+
+// Sleeps for TimTick times b
+func sleepTicks(c Clock, b byte) {
+	c.Sleep(time.Duration(b) * TimTick)
+}
+
+// Returns the elapsed time in ticks as TimBytes big-endian bytes
+func elapsedTicks(c Clock) []byte {
+	ticks := make([]byte, TimBytes)
+	binary.BigEndian.PutUint32(ticks, uint32(c.Elapsed()/TimTick))
+	return ticks
+}
@@ -0,0 +1,60 @@
+package interpreter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+* Tests
+**/
+
+func TestTime(t *testing.T) {
+	t.Run("FakeClock", func(t *testing.T) {
+		clock := &FakeClock{}
+		clock.Sleep(3 * time.Second)
+		p, err := NewProgram(strings.NewReader(`tl:tim +++[>+++++<-]>%' sleep 150ms then read the time`), WithClock(clock))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		if err = p.Run(100); err != nil {
+			t.Fatalf("Expected no error on Run, instead got %v", err)
+		}
+		if clock.Elapsed() != 3150*time.Millisecond {
+			t.Fatalf("Expected the clock to advance to 3.15s, instead got %s", clock.Elapsed())
+		}
+		// 315 ticks
+		expectedMem := []byte{0, 0, 0, 1, 59}
+		if !bytes.Equal(p.Memory.Bytes(), expectedMem) {
+			t.Fatalf("Expected %+d, but memory is %+d", expectedMem, p.Memory.Bytes())
+		}
+	})
+	t.Run("SystemClock", func(t *testing.T) {
+		p, err := NewProgram(strings.NewReader(`tl:tim ++%'`))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		start := time.Now()
+		if err = p.Run(100); err != nil {
+			t.Fatalf("Expected no error on Run, instead got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 2*TimTick {
+			t.Fatalf("Expected to sleep for at least 20ms, instead slept for %s", elapsed)
+		}
+		if p.Memory.Bytes()[3] < 2 {
+			t.Fatalf("Expected at least 2 ticks, instead got %+d", p.Memory.Bytes())
+		}
+	})
+	t.Run("OutOfBoundary", func(t *testing.T) {
+		p, err := NewProgram(strings.NewReader(`tl:tim '`))
+		if err != nil {
+			t.Fatalf("Failed to load test program: %v", err)
+		}
+		p.Memory.p = MemSize - TimBytes + 1
+		if err = p.Run(100); err != ErrMemOutOfBoundary {
+			t.Fatalf("Expected ErrMemOutOfBoundary, instead got %v", err)
+		}
+	})
+}
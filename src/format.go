@@ -0,0 +1,189 @@
+package interpreter
+
+import "strings"
+
+const (
+	// The default maximum line width of formatted source code
+	FormatWidth = 80
+	// Indentation of a loop level
+	FormatIndent = "  "
+)
+
+// A node of the source code being formatted
+type formatItem struct {
+	token Token
+	// Instructions of a group of the same instruction
+	text string
+	// The comment is on the same line as the previous token
+	trailing bool
+	// The item follows a blank line
+	blank bool
+	// Body of a loop
+	loop   bool
+	items  []formatItem
+	closed bool
+}
+
+// Formats source code: the header and the own-line comments get their own
+// line, loops are indented by depth unless they fit in the line with no
+// comments or nested loops, runs of the same instruction are grouped and
+// separated by spaces, lines are broken at width and single blank lines
+// are kept. Trailing comments stay after the code they follow.
+// Returns the formatted source code
+func Format(src []byte, width int) []byte {
+	_, tokens := Scan(src)
+	f := formatter{width: width}
+	start := 0
+	if len(tokens) > 0 && tokens[0].Kind == TokenHeader {
+		f.line = tokens[0].Text
+		f.breakNext = true
+		start = 1
+	}
+	items, _, _ := parseFormatItems(tokens, start, false)
+	f.render(items)
+	f.flush()
+	out := f.out.String()
+	if start == 0 {
+		if _, n := ParseHeader([]byte(out)); n > 0 {
+			// The first comment must not become a header
+			out = "\n" + out
+		}
+	}
+	return []byte(out)
+}
+
+// Groups the tokens starting at i until the end of the loop (if inLoop) or of the tokens
+// Returns the items, the index of the next token and true if the loop was closed
+func parseFormatItems(tokens []Token, i int, inLoop bool) ([]formatItem, int, bool) {
+	items := []formatItem{}
+	for i < len(tokens) {
+		token := tokens[i]
+		item := formatItem{token: token}
+		if i > 0 {
+			prevLine := tokens[i-1].Line
+			item.blank = token.Line-prevLine >= 2
+			item.trailing = token.Kind == TokenComment && token.Line == prevLine
+		}
+		switch {
+		case token.Kind == TokenComment:
+			item.text = token.Text
+			i++
+		case token.Text == "]" && inLoop:
+			return items, i + 1, true
+		case token.Text == "[":
+			item.loop = true
+			item.items, i, item.closed = parseFormatItems(tokens, i+1, true)
+		default:
+			// Group the same instruction, breaking at blank lines
+			item.text = token.Text
+			for i++; i < len(tokens) && tokens[i].Kind == TokenInstruction && tokens[i].Text == token.Text &&
+				token.Text != "]" && tokens[i].Line-tokens[i-1].Line < 2; i++ {
+				item.text += token.Text
+			}
+		}
+		items = append(items, item)
+	}
+	return items, i, false
+}
+
+// Writes formatted source code
+type formatter struct {
+	out   strings.Builder
+	width int
+	// Current line, its indentation and the current indentation
+	line       string
+	lineIndent int
+	indent     int
+	// The next code starts a new line
+	breakNext bool
+}
+
+// Writes the current line
+func (f *formatter) flush() {
+	if f.line != "" {
+		f.out.WriteString(strings.Repeat(FormatIndent, f.lineIndent) + f.line + "\n")
+		f.line = ""
+	}
+	f.breakNext = false
+}
+
+// Starts a new line with text
+func (f *formatter) start(text string) {
+	f.flush()
+	f.line = text
+	f.lineIndent = f.indent
+}
+
+// Writes a blank line, unless at the start or after another blank line
+func (f *formatter) blankLine() {
+	f.flush()
+	out := f.out.String()
+	if out != "" && !strings.HasSuffix(out, "\n\n") {
+		f.out.WriteString("\n")
+	}
+}
+
+// Adds code to the current line, breaking it at the width
+func (f *formatter) code(text string) {
+	available := f.width - len(FormatIndent)*f.indent
+	if available < 1 {
+		available = 1
+	}
+	for len(text) > available {
+		f.code(text[:available])
+		text = text[available:]
+	}
+	if f.breakNext || (f.line != "" && len(FormatIndent)*f.lineIndent+len(f.line)+1+len(text) > f.width) {
+		f.flush()
+	}
+	if f.line == "" {
+		f.start(text)
+		return
+	}
+	f.line += " " + text
+}
+
+// Writes the items
+func (f *formatter) render(items []formatItem) {
+	for _, item := range items {
+		if item.blank {
+			f.blankLine()
+		}
+		switch {
+		case item.loop:
+			f.loop(item)
+		case item.token.Kind == TokenComment && item.trailing && f.line != "":
+			f.line += " " + item.text
+			f.flush()
+		case item.token.Kind == TokenComment:
+			f.start(item.text)
+			f.flush()
+		default:
+			f.code(item.text)
+		}
+	}
+}
+
+// Writes a loop, inline if possible
+func (f *formatter) loop(item formatItem) {
+	inline := item.closed
+	groups := []string{}
+	for _, body := range item.items {
+		inline = inline && !body.loop && !body.blank && body.token.Kind == TokenInstruction
+		groups = append(groups, body.text)
+	}
+	text := "[" + strings.Join(groups, " ") + "]"
+	if inline && len(FormatIndent)*f.indent+len(text) <= f.width {
+		f.code(text)
+		return
+	}
+	f.start("[")
+	f.breakNext = true
+	f.indent++
+	f.render(item.items)
+	f.indent--
+	if item.closed {
+		f.start("]")
+		f.breakNext = true
+	}
+}
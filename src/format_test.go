@@ -0,0 +1,64 @@
+package interpreter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Returns the instructions of some source code
+func formatInstructions(t *testing.T, src []byte) []byte {
+	inst, err := NewInstructions(bytes.NewReader(src))
+	if err != nil {
+		t.Fatalf("Failed to load instructions: %v", err)
+	}
+	return inst.instruction
+}
+
+/*
+* Tests
+**/
+
+func TestFormat(t *testing.T) {
+	tests := map[string]string{
+		"":                      "",
+		"+++>>-":                "+++ >> -\n",
+		"[->+<]":                "[- > + <]\n",
+		"tl:net server\n+@.":    "tl:net server\n+ @ .\n",
+		"+ add\n- sub":          "+ add\n- sub\n",
+		"note\n+\n\n\n\n-":      "note\n+\n\n-\n",
+		"+[-[>]<]":              "+\n[\n  - [>] <\n]\n",
+		"[- loop\n]":            "[\n  - loop\n]\n",
+		"[+ unclosed":           "[\n  + unclosed\n",
+		"tl:net hi\n+":          "tl:net hi\n+\n",
+		" tl:net hi\n+":         "\ntl:net hi\n+\n",
+		"]]+":                   "] ] +\n",
+		strings.Repeat("+", 12): "++++++++++\n++\n",
+	}
+	for src, expected := range tests {
+		formatted := string(Format([]byte(src), 10))
+		if formatted != expected {
+			t.Errorf("Failed to format %q: expected %q, got %q", src, expected, formatted)
+		}
+	}
+}
+
+func TestFormatPreservesProgram(t *testing.T) {
+	sources := []string{
+		"++++++++[>++++[>++>+++>+++>+<<<<-]>+>+>->>+[<]<-]>>.>---.+++++++..+++.>>.<-.<.+++.------.--------.>>+.>++.",
+		"tl:net:thr server\n+@ listen\n\n\n?. print [-] clear\n  a comment [ in loop - ] end\n]]",
+		"  tl:net not a header @",
+		"[-]++ unclosed [ >>",
+	}
+	for _, src := range sources {
+		for _, width := range []int{1, 10, FormatWidth} {
+			formatted := Format([]byte(src), width)
+			if !bytes.Equal(formatInstructions(t, formatted), formatInstructions(t, []byte(src))) {
+				t.Errorf("Formatting %q at width %d changed the program: %q", src, width, formatted)
+			}
+			if again := Format(formatted, width); !bytes.Equal(again, formatted) {
+				t.Errorf("Formatting %q at width %d is not stable: %q then %q", src, width, formatted, again)
+			}
+		}
+	}
+}
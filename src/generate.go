@@ -0,0 +1,220 @@
+package interpreter
+
+import (
+	"bytes"
+	"sort"
+)
+
+// What the generated code is optimized for
+type GenGoal uint8
+
+const (
+	// The fewest instructions
+	GenGoalSize GenGoal = 0
+	// The fewest executed instructions
+	GenGoalSpeed GenGoal = 1
+)
+
+// Maps the goal names to the goals
+var GenGoals = map[string]GenGoal{
+	"size":  GenGoalSize,
+	"speed": GenGoalSpeed,
+}
+
+const (
+	// Most cells set up by the multiplication loop
+	genMaxCells = 8
+	// Largest multiplier of the multiplication loop
+	genMaxMultiplier = 20
+)
+
+// Code printing some text and its cost
+type genCandidate struct {
+	code []byte
+	// Executed instructions
+	steps int
+}
+
+// Returns code printing text, starting from a blank memory and leaving the
+// pointer anywhere. Cells are set close to the bytes of the text with a
+// multiplication loop, then each byte is printed from the closest cell.
+func GenerateText(text []byte, goal GenGoal) []byte {
+	best := genPrint(text, 0, nil)
+	centers := genCenters(text)
+	for n := 1; n <= len(centers); n++ {
+		split := genClusters(centers, n)
+		refined := genRefine(centers, genClusters(centers, n))
+		for _, clusters := range [][]int{split, genOrder(text, split), refined, genOrder(text, refined)} {
+			for m := 2; m <= genMaxMultiplier; m++ {
+				candidate := genPrint(text, m, clusters)
+				if genBetter(candidate, best, goal) {
+					best = candidate
+				}
+			}
+		}
+	}
+	return best.code
+}
+
+// Returns true if a is better than b for the goal
+func genBetter(a, b genCandidate, goal GenGoal) bool {
+	if goal == GenGoalSpeed {
+		return a.steps < b.steps || (a.steps == b.steps && len(a.code) < len(b.code))
+	}
+	return len(a.code) < len(b.code) || (len(a.code) == len(b.code) && a.steps < b.steps)
+}
+
+// Returns the distinct bytes of text, sorted
+func genCenters(text []byte) []int {
+	seen := map[byte]bool{}
+	centers := []int{}
+	for _, b := range text {
+		if !seen[b] {
+			seen[b] = true
+			centers = append(centers, int(b))
+		}
+	}
+	sort.Ints(centers)
+	if len(centers) > genMaxCells {
+		return genClusters(centers, genMaxCells)
+	}
+	return centers
+}
+
+// Groups sorted values in n ranges of similar size
+// Returns the average of each range
+func genClusters(values []int, n int) []int {
+	clusters := make([]int, n)
+	for i := 0; i < n; i++ {
+		from, to := i*len(values)/n, (i+1)*len(values)/n
+		sum := 0
+		for _, v := range values[from:to] {
+			sum += v
+		}
+		clusters[i] = sum / (to - from)
+	}
+	return clusters
+}
+
+// Moves each cluster to the average of the values closest to it (k-means)
+// Returns the new clusters
+func genRefine(values []int, clusters []int) []int {
+	for iteration := 0; iteration < 8; iteration++ {
+		sums := make([]int, len(clusters))
+		counts := make([]int, len(clusters))
+		for _, v := range values {
+			closest := 0
+			for i, center := range clusters {
+				if genDistance(v, center) < genDistance(v, clusters[closest]) {
+					closest = i
+				}
+			}
+			sums[closest] += v
+			counts[closest]++
+		}
+		for i := range clusters {
+			if counts[i] > 0 {
+				clusters[i] = sums[i] / counts[i]
+			}
+		}
+	}
+	return clusters
+}
+
+// Sorts the clusters by the first byte of text they are the closest to, reducing the moves
+// Returns the sorted clusters
+func genOrder(text []byte, clusters []int) []int {
+	first := make([]int, len(clusters))
+	for i := range first {
+		first[i] = len(text)
+	}
+	for pos := len(text) - 1; pos >= 0; pos-- {
+		closest := 0
+		for i, center := range clusters {
+			if genDistance(int(text[pos]), center) < genDistance(int(text[pos]), clusters[closest]) {
+				closest = i
+			}
+		}
+		first[closest] = pos
+	}
+	order := make([]int, len(clusters))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return first[order[i]] < first[order[j]] })
+	sorted := make([]int, len(clusters))
+	for i, o := range order {
+		sorted[i] = clusters[o]
+	}
+	return sorted
+}
+
+// Returns the code setting cell i+1 to the closest multiple of m of clusters[i]
+// with a loop (if m > 0), then printing text from the closest cells
+func genPrint(text []byte, m int, clusters []int) genCandidate {
+	var code bytes.Buffer
+	c := genCandidate{}
+	cells := []int{0}
+	if m > 0 {
+		// Multiplication loop
+		body := []byte{}
+		for _, center := range clusters {
+			factor := (center + m/2) / m
+			body = append(body, '>')
+			body = append(body, bytes.Repeat([]byte{'+'}, factor)...)
+			cells = append(cells, factor*m%256)
+		}
+		body = append(body, bytes.Repeat([]byte{'<'}, len(clusters))...)
+		body = append(body, '-')
+		code.Write(bytes.Repeat([]byte{'+'}, m))
+		code.WriteByte('[')
+		code.Write(body)
+		code.WriteByte(']')
+		c.steps = m + 1 + m*(len(body)+1)
+	}
+	pointer := 0
+	for _, b := range text {
+		// The closest cell, counting moves and changes
+		closest, cost := 0, -1
+		for i, v := range cells {
+			if d := genDistance(pointer, i) + genChange(v, int(b)); cost < 0 || d < cost {
+				closest, cost = i, d
+			}
+		}
+		start := code.Len()
+		for ; pointer < closest; pointer++ {
+			code.WriteByte('>')
+		}
+		for ; pointer > closest; pointer-- {
+			code.WriteByte('<')
+		}
+		delta := (int(b) - cells[closest] + 256) % 256
+		if delta <= 128 {
+			code.Write(bytes.Repeat([]byte{'+'}, delta))
+		} else {
+			code.Write(bytes.Repeat([]byte{'-'}, 256-delta))
+		}
+		cells[closest] = int(b)
+		code.WriteByte('.')
+		c.steps += code.Len() - start
+	}
+	c.code = code.Bytes()
+	return c
+}
+
+// Returns the moves from cell a to cell b
+func genDistance(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// Returns the increments or decrements changing a cell from a to b
+func genChange(a, b int) int {
+	d := (b - a + 256) % 256
+	if d > 128 {
+		return 256 - d
+	}
+	return d
+}
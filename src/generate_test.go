@@ -0,0 +1,55 @@
+package interpreter
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Runs code generated for text and checks its output
+// Returns the executed instructions
+func genRun(t *testing.T, code []byte, text []byte) int {
+	p, err := NewProgram(bytes.NewReader(code))
+	if err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	output := &bytes.Buffer{}
+	p.IOWriter = output
+	steps := 0
+	for ; err == nil; steps++ {
+		err = p.RunNext()
+	}
+	if err != ErrProgramDone {
+		t.Fatalf("Failed to run the code generated for %q: %v", text, err)
+	}
+	if !bytes.Equal(output.Bytes(), text) {
+		t.Fatalf("Expected the code generated for %q to print it, got %q", text, output.Bytes())
+	}
+	return steps - 1
+}
+
+/*
+* Tests
+**/
+
+func TestGenerateText(t *testing.T) {
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	texts := [][]byte{{}, []byte("A"), []byte("Hello World!\n"), []byte("héllo ☃"), all,
+		bytes.Repeat([]byte("=== BANNER ===\n"), 4)}
+	for _, text := range texts {
+		size := GenerateText(text, GenGoalSize)
+		speed := GenerateText(text, GenGoalSpeed)
+		sizeSteps := genRun(t, size, text)
+		speedSteps := genRun(t, speed, text)
+		if len(size) > len(speed) || speedSteps > sizeSteps {
+			t.Errorf("Expected the size goal to be shorter (%d, %d) and the speed goal to be faster (%d, %d) for %q",
+				len(size), len(speed), sizeSteps, speedSteps, text)
+		}
+	}
+	hello := GenerateText([]byte("Hello World!\n"), GenGoalSize)
+	if len(hello) > 125 {
+		t.Fatalf("Expected short code for Hello World, got %d instructions: %s", len(hello), hello)
+	}
+}
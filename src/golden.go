@@ -0,0 +1,277 @@
+package interpreter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrGoldenInvalidLimit = errors.New("the limit file must contain a positive number of instructions")
+	ErrGoldenTimeout      = errors.New("reached the time limit")
+)
+
+const (
+	// Instructions a golden test can execute without a .limit file
+	GoldenLimit = 10000000
+	// Seed of the random extension in golden tests
+	GoldenRandomSeed = 1
+	// Wall time a golden test can take
+	GoldenTimeout = 10 * time.Second
+)
+
+// A program with its input and expected results, read from foo.bf, foo.in,
+// foo.out and the optional foo.err and foo.limit files
+type GoldenTest struct {
+	// Path of the program without the .bf extension, relative to the test directory
+	Name string
+	// Path of the program
+	Path string
+	// The program input (empty without a .in file)
+	Input []byte
+	// The expected output
+	Output []byte
+	// The expected error message (empty without a .err file)
+	Err string
+	// Instructions the program can execute
+	Limit int
+	// Wall time the program can take (GoldenTimeout if 0)
+	Timeout time.Duration
+}
+
+// The result of a golden test
+type GoldenResult struct {
+	Test     GoldenTest
+	Output   []byte
+	Err      string
+	Duration time.Duration
+	// Why the test failed, empty if it passed
+	Failure string
+}
+
+// Returns true if the test passed
+func (r GoldenResult) Passed() bool {
+	return r.Failure == ""
+}
+
+// Finds the golden tests in dir and its subdirectories: every program with a .out file
+// Returns the tests sorted by name and an error
+func FindGoldenTests(dir string) ([]GoldenTest, error) {
+	tests := []GoldenTest{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(path) != ".bf" {
+			return err
+		}
+		base := strings.TrimSuffix(path, ".bf")
+		output, err := os.ReadFile(base + ".out")
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, base)
+		if err != nil {
+			return err
+		}
+		test := GoldenTest{Name: filepath.ToSlash(name), Path: path, Output: output, Limit: GoldenLimit, Timeout: GoldenTimeout}
+		if test.Input, err = readOptional(base + ".in"); err != nil {
+			return err
+		}
+		expectedErr, err := readOptional(base + ".err")
+		if err != nil {
+			return err
+		}
+		test.Err = strings.TrimSpace(string(expectedErr))
+		limit, err := readOptional(base + ".limit")
+		if err != nil {
+			return err
+		}
+		if len(limit) > 0 {
+			test.Limit, err = strconv.Atoi(strings.TrimSpace(string(limit)))
+			if err != nil || test.Limit <= 0 {
+				return fmt.Errorf("%s.limit: %w", base, ErrGoldenInvalidLimit)
+			}
+		}
+		tests = append(tests, test)
+		return nil
+	})
+	return tests, err
+}
+
+// Returns the content of a file, empty if it doesn't exist
+func readOptional(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []byte{}, nil
+	}
+	return data, err
+}
+
+// Runs a golden test with the network extension disabled, no files, a
+// simulated clock and a fixed random seed
+// A test reaching its timeout fails, whatever its expected error
+// Returns the result
+func RunGoldenTest(test GoldenTest) (result GoldenResult) {
+	result = GoldenResult{Test: test}
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+	file, err := os.Open(test.Path)
+	if err != nil {
+		result.Err = fmt.Sprintf("failed to load program: %v", err)
+		result.Failure = result.Err
+		return result
+	}
+	defer file.Close()
+	program, err := NewProgram(file,
+		WithNetwork(NewNetwork(WithPolicy(NetPolicyDisabled))),
+		WithRandomSeed(GoldenRandomSeed),
+		WithClock(&FakeClock{}))
+	if err != nil {
+		result.Err = fmt.Sprintf("failed to load program: %v", err)
+		result.Failure = result.Err
+		return result
+	}
+	defer program.Close()
+	output := &bytes.Buffer{}
+	program.IOReader = bytes.NewReader(test.Input)
+	program.IOWriter = output
+	// Run, stopping between instructions at the timeout: no instruction can
+	// block as the input is in memory, the network is disabled and the clock is simulated
+	timeout := test.Timeout
+	if timeout <= 0 {
+		timeout = GoldenTimeout
+	}
+	var timedOut atomic.Bool
+	timer := time.AfterFunc(timeout, func() {
+		timedOut.Store(true)
+	})
+	defer timer.Stop()
+	err = ErrExecutionLimit
+	for i := 0; i < test.Limit && err == ErrExecutionLimit; i++ {
+		if timedOut.Load() {
+			err = ErrGoldenTimeout
+		} else if stepErr := program.RunNext(); stepErr == ErrProgramDone {
+			err = nil
+		} else if stepErr != nil {
+			err = stepErr
+		}
+	}
+	if err != nil {
+		result.Err = err.Error()
+	}
+	result.Output = output.Bytes()
+	if err == ErrGoldenTimeout {
+		result.Failure = fmt.Sprintf("%v of %s", err, timeout)
+		return result
+	}
+	// Compare
+	failures := []string{}
+	if result.Err != test.Err {
+		failures = append(failures, fmt.Sprintf("expected the error %q, got %q", test.Err, result.Err))
+	}
+	if !bytes.Equal(result.Output, test.Output) {
+		failures = append(failures, "the output differs (-expected +actual):\n"+
+			LineDiff(string(test.Output), string(result.Output)))
+	}
+	result.Failure = strings.Join(failures, "\n")
+	return result
+}
+
+// Runs the golden tests, up to parallel at the time
+// Returns the results in the order of the tests
+func RunGoldenTests(tests []GoldenTest, parallel int) []GoldenResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]GoldenResult, len(tests))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = RunGoldenTest(tests[i])
+			}
+		}()
+	}
+	for i := range tests {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// JUnit XML report
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// Writes the results as a JUnit XML report with a single test suite
+// Returns an error
+func WriteJUnit(w io.Writer, suite string, results []GoldenResult) error {
+	report := junitSuite{Name: suite, Tests: len(results), Cases: []junitCase{}}
+	var total time.Duration
+	for _, result := range results {
+		total += result.Duration
+		c := junitCase{
+			Name:      result.Test.Name,
+			ClassName: suite,
+			Time:      fmt.Sprintf("%.3f", result.Duration.Seconds()),
+		}
+		if !result.Passed() {
+			report.Failures++
+			message := strings.SplitN(result.Failure, "\n", 2)[0]
+			c.Failure = &junitFailure{Message: message, Text: result.Failure}
+			c.SystemOut = string(result.Output)
+		}
+		report.Cases = append(report.Cases, c)
+	}
+	report.Time = fmt.Sprintf("%.3f", total.Seconds())
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitSuites{Suites: []junitSuite{report}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
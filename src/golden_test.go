@@ -0,0 +1,120 @@
+package interpreter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Writes the files of golden tests in a new directory
+// Returns the directory
+func writeGoldenFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+	return dir
+}
+
+/*
+* Tests
+**/
+
+func TestGolden(t *testing.T) {
+	dir := writeGoldenFiles(t, map[string]string{
+		"echo.bf":          ",[.,]",
+		"echo.in":          "hi",
+		"echo.out":         "hi",
+		"echo.err":         "failed to read input\n",
+		"nested/wrong.bf":  "+++.",
+		"nested/wrong.out": "\x02",
+		"loop.bf":          "+[]",
+		"loop.out":         "",
+		"loop.limit":       "100",
+		"loop.err":         ErrExecutionLimit.Error(),
+		"exit.bf":          "tl:ext +++!",
+		"exit.out":         "",
+		"notest.bf":        "+",
+	})
+	tests, err := FindGoldenTests(dir)
+	if err != nil {
+		t.Fatalf("Failed to find the tests: %v", err)
+	}
+	names := []string{}
+	for _, test := range tests {
+		names = append(names, test.Name)
+	}
+	if strings.Join(names, " ") != "echo exit loop nested/wrong" {
+		t.Fatalf("Found the wrong tests: %v", names)
+	}
+	if tests[2].Limit != 100 || tests[0].Limit != GoldenLimit || string(tests[0].Input) != "hi" {
+		t.Fatalf("Failed to read the test files: %+v", tests)
+	}
+	results := RunGoldenTests(tests, 3)
+	passed := []bool{true, false, true, false}
+	for i, result := range results {
+		if result.Test.Name != tests[i].Name || result.Passed() != passed[i] {
+			t.Errorf("Expected %s to pass: %v, got failure %q", tests[i].Name, passed[i], result.Failure)
+		}
+	}
+	if !strings.Contains(results[1].Failure, `expected the error "", got "the program exited with code 3"`) {
+		t.Errorf("Expected an error mismatch, got %q", results[1].Failure)
+	}
+	if !strings.Contains(results[3].Failure, "-\x02\n+\x03\n") {
+		t.Errorf("Expected an output diff, got %q", results[3].Failure)
+	}
+	// JUnit report
+	report := &bytes.Buffer{}
+	if err := WriteJUnit(report, "golden", results); err != nil {
+		t.Fatalf("Failed to write the JUnit report: %v", err)
+	}
+	var parsed junitSuites
+	if err := xml.Unmarshal(report.Bytes(), &parsed); err != nil {
+		t.Fatalf("Failed to parse the JUnit report: %v\n%s", err, report)
+	}
+	if len(parsed.Suites) != 1 || parsed.Suites[0].Tests != 4 || parsed.Suites[0].Failures != 2 ||
+		parsed.Suites[0].Cases[1].Failure == nil || parsed.Suites[0].Cases[0].Failure != nil {
+		t.Fatalf("Wrong JUnit report: %s", report)
+	}
+	// Invalid limit
+	dir = writeGoldenFiles(t, map[string]string{"a.bf": "+", "a.out": "", "a.limit": "many"})
+	if _, err := FindGoldenTests(dir); !errors.Is(err, ErrGoldenInvalidLimit) {
+		t.Fatalf("Expected ErrGoldenInvalidLimit, got %v", err)
+	}
+	// A test reaching its timeout fails, even when it expects the error
+	dir = writeGoldenFiles(t, map[string]string{"slow.bf": "+[]", "slow.out": "", "slow.err": ErrGoldenTimeout.Error()})
+	tests, err = FindGoldenTests(dir)
+	if err != nil || len(tests) != 1 || tests[0].Timeout != GoldenTimeout {
+		t.Fatalf("Failed to find the slow test: %+v (%v)", tests, err)
+	}
+	tests[0].Limit = GoldenLimit * 1000
+	tests[0].Timeout = 10 * time.Millisecond
+	start := time.Now()
+	result := RunGoldenTest(tests[0])
+	if result.Passed() || result.Err != ErrGoldenTimeout.Error() || time.Since(start) > 5*time.Second {
+		t.Fatalf("Expected the slow test to time out, got %q (%q) after %s", result.Failure, result.Err, time.Since(start))
+	}
+}
+
+func TestGoldenSamples(t *testing.T) {
+	tests, err := FindGoldenTests("../samples")
+	if err != nil || len(tests) == 0 {
+		t.Fatalf("Failed to find the sample tests: %v", err)
+	}
+	for _, result := range RunGoldenTests(tests, 4) {
+		if !result.Passed() {
+			t.Errorf("Sample %s failed: %s", result.Test.Name, result.Failure)
+		}
+	}
+}
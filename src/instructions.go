@@ -1,18 +1,27 @@
 package interpreter
 
-import (
-	"bytes"
-	"io"
-)
+import "io"
 
 type ExtensionCode uint8
 
 var (
 	ExtNet ExtensionCode = 0b00000001
+	ExtThr ExtensionCode = 0b00000010
+	ExtFio ExtensionCode = 0b00000100
+	ExtTim ExtensionCode = 0b00001000
+	ExtRnd ExtensionCode = 0b00010000
+	ExtExt ExtensionCode = 0b00100000
+	ExtUdp ExtensionCode = 0b01000000
 )
 
 var SupportedExtensions = map[string]ExtensionCode{
 	"net": ExtNet,
+	"thr": ExtThr,
+	"fio": ExtFio,
+	"tim": ExtTim,
+	"rnd": ExtRnd,
+	"ext": ExtExt,
+	"udp": ExtNet | ExtUdp, // Network extension in datagram mode
 }
 
 // Structure containing the instructions and a program counter
@@ -20,6 +29,7 @@ type Instructions struct {
 	instruction []byte        // Our instructions represented in ASCII bytes
 	extensions  ExtensionCode // Enabled extensions
 	pc          int           // Program Coutner
+	safe        []bool        // Moves proven to stay in the memory (nil if not analyzed)
 }
 
 // Resets the PC
@@ -27,6 +37,21 @@ func (i *Instructions) Reset() {
 	i.pc = 0
 }
 
+// Returns a copy of the instructions with its own program counter
+func (i *Instructions) fork() *Instructions {
+	return &Instructions{
+		instruction: i.instruction,
+		extensions:  i.extensions,
+		pc:          i.pc,
+		safe:        i.safe,
+	}
+}
+
+// Returns true if the last instruction is a move proven to stay in the memory
+func (i *Instructions) safeMove() bool {
+	return i.safe != nil && i.safe[i.pc-1]
+}
+
 // Get the current instruction, increment the counter
 // returns the current instruction or 0 (terminate program)
 func (i *Instructions) Pop() byte {
@@ -130,10 +155,15 @@ func (i *Instructions) CountRepeating() int {
 // Parse the instructions from a given io reader.
 // Returns Instructions and an error
 func NewInstructions(reader io.Reader) (*Instructions, error) {
+	return newInstructions(reader, 0)
+}
+
+// Same as NewInstructions, with the given extensions enabled on top of the header ones
+func newInstructions(reader io.Reader, ext ExtensionCode) (*Instructions, error) {
 	// Setup
 	instructions := Instructions{
 		instruction: []byte{},
-		extensions:  0,
+		extensions:  ext,
 		pc:          0,
 	}
 	inst := []byte{}
@@ -150,38 +180,8 @@ func NewInstructions(reader io.Reader) (*Instructions, error) {
 		}
 	}
 	// Check for extensions "tl:"
-	// Supported extensions are: "net"
-	// Fail quietly to improve compatibility with bf
-	if len(inst) > 6 && inst[0] == 't' && inst[1] == 'l' && inst[2] == ':' {
-		ext := make([]byte, 0, 3)
-		for i := 3; i < len(inst); i++ {
-			if inst[i] == ':' {
-				extCode, supported := SupportedExtensions[string(ext)]
-				if !supported {
-					// Not supported, stop parsing
-					break
-				}
-				instructions.extensions |= extCode
-				ext = make([]byte, 0, 3)
-				continue
-			}
-			if len(ext) == 3 {
-				extCode, supported := SupportedExtensions[string(ext)]
-				if !supported {
-					// Not supported, stop parsing
-					break
-				}
-				instructions.extensions |= extCode
-				// Anything else is too long to be a valid extension
-				break
-			}
-			if bytes.IndexByte([]byte("net"), inst[i]) == -1 {
-				// Not a valid char
-				break
-			}
-			ext = append(ext, inst[i])
-		}
-	}
+	headerExt, _ := ParseHeader(inst)
+	instructions.extensions |= headerExt
 	// Filter valid instructions
 	n := 0
 	for i := 0; i < len(inst); i++ {
@@ -195,6 +195,56 @@ func NewInstructions(reader io.Reader) (*Instructions, error) {
 	return &instructions, nil
 }
 
+// Parses the extensions header ("tl:" followed by a ":"-separated list of extension codes)
+// Supported extensions are: "net", "thr", "fio", "tim", "rnd", "ext", "udp"
+// Fails quietly to improve compatibility with bf
+// Returns the enabled extensions and the length of the header
+func ParseHeader(src []byte) (ExtensionCode, int) {
+	var extensions ExtensionCode
+	n := 0
+	if len(src) <= 6 || src[0] != 't' || src[1] != 'l' || src[2] != ':' {
+		return 0, 0
+	}
+	ext := make([]byte, 0, 3)
+	for i := 3; i < len(src); i++ {
+		if src[i] == ':' {
+			extCode, supported := SupportedExtensions[string(ext)]
+			if !supported {
+				// Not supported, stop parsing
+				break
+			}
+			extensions |= extCode
+			n = i + 1
+			ext = make([]byte, 0, 3)
+			continue
+		}
+		if len(ext) == 3 {
+			extCode, supported := SupportedExtensions[string(ext)]
+			if !supported {
+				// Not supported, stop parsing
+				break
+			}
+			extensions |= extCode
+			n = i
+			// Anything else is too long to be a valid extension
+			break
+		}
+		if src[i] < 'a' || src[i] > 'z' {
+			// Not a valid char
+			break
+		}
+		ext = append(ext, src[i])
+		if i == len(src)-1 && len(ext) == 3 {
+			// The header ends with the source
+			if extCode, supported := SupportedExtensions[string(ext)]; supported {
+				extensions |= extCode
+				n = len(src)
+			}
+		}
+	}
+	return extensions, n
+}
+
 // Returns true if b is a valid instruction, false otherwise
 // ext represents the enabled extensions, all non-compliant bytes will be ignored
 func IsValidInstruction(b byte, ext ExtensionCode) bool {
@@ -203,5 +253,18 @@ func IsValidInstruction(b byte, ext ExtensionCode) bool {
 		b == byte('.') || b == byte(',') || // Base: Write/Read Input
 		b == byte('[') || b == byte(']') || // Base: Conditional Loop
 		(ext&ExtNet == ExtNet) && // Extension: Network
-			(b == byte('?') || b == byte('^') || b == byte('@') || b == byte('*') || b == byte(';')))
+			(b == byte('?') || b == byte('^') || b == byte('@') || b == byte('*') || b == byte(';') ||
+				b == byte('=') || b == byte('\\') || b == byte('/') || b == byte('|')) ||
+		(ext&ExtThr == ExtThr) && // Extension: Threads
+			(b == byte('Y') || b == byte('W')) ||
+		(ext&ExtFio == ExtFio) && // Extension: Files
+			(b == byte('$') || b == byte('(') || b == byte(')')) ||
+		(ext&ExtTim == ExtTim) && // Extension: Time
+			(b == byte('%') || b == byte('\'')) ||
+		(ext&ExtRnd == ExtRnd) && // Extension: Random
+			(b == byte('&') || b == byte('"')) ||
+		(ext&ExtExt == ExtExt) && // Extension: Exit
+			b == byte('!') ||
+		(ext&ExtUdp == ExtUdp) && // Extension: Network (datagram mode)
+			b == byte('`'))
 }
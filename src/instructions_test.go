@@ -56,6 +56,13 @@ func TestNewInstructions(t *testing.T) {
 	if !bytes.Equal([]byte{'[', ']'}, i.instruction) {
 		t.Fatalf("Failed to parse instruction. Got %v", i.instruction)
 	}
+	i, err = NewInstructions(strings.NewReader("tl:net:thr"))
+	if err != nil {
+		t.Fatalf("Expected no error, instead got %v\n", err)
+	}
+	if i.extensions != ExtNet|ExtThr {
+		t.Fatalf("Failed to parse extensions at the end of the source. Got %b", i.extensions)
+	}
 }
 
 func TestIsValidInstruction(t *testing.T) {
@@ -71,11 +78,15 @@ func TestIsValidInstruction(t *testing.T) {
 	}
 
 	validBytesNetwork := map[byte]bool{
-		'*': true,
-		'@': true,
-		'?': true,
-		'^': true,
-		';': true,
+		'*':  true,
+		'@':  true,
+		'?':  true,
+		'^':  true,
+		';':  true,
+		'=':  true,
+		'\\': true,
+		'/':  true,
+		'|':  true,
 	}
 
 	for i := 0; i < 256; i++ {
@@ -0,0 +1,190 @@
+package interpreter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Checks reported by Lint
+const (
+	LintUnbalanced        = "unbalanced"
+	LintDeadLoop          = "dead-loop"
+	LintInfiniteLoop      = "infinite-loop"
+	LintOutOfBoundary     = "out-of-boundary"
+	LintDisabledExtension = "disabled-extension"
+	LintUnusedExtension   = "unused-extension"
+)
+
+// A likely bug found by Lint
+type Diagnostic struct {
+	// Position of the problem, starting from 1
+	Line int `json:"line"`
+	Col  int `json:"col"`
+	// One of the Lint* checks
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// The problems found while linting
+type linter struct {
+	diagnostics []Diagnostic
+}
+
+// Looks for likely bugs in source code: unbalanced brackets, loops that can't
+// be entered, loops that never end once entered, moves out of the memory,
+// instructions of disabled extensions and extensions that are never used
+// Returns the problems sorted by position
+func Lint(src []byte) []Diagnostic {
+	ext, tokens := Scan(src)
+	l := linter{}
+	instructions := []Token{}
+	var used ExtensionCode
+	for _, token := range tokens {
+		switch token.Kind {
+		case TokenComment:
+			l.disabledExtensions(token, ext)
+		case TokenInstruction:
+			instructions = append(instructions, token)
+			used |= instructionExtension(token.Text[0], ext)
+		}
+	}
+	if len(tokens) > 0 && tokens[0].Kind == TokenHeader {
+		l.unusedExtensions(tokens[0], used)
+	}
+	if l.balancedBrackets(instructions) {
+		l.lint(instructions, ext)
+	}
+	l.sort()
+	return l.diagnostics
+}
+
+// Adds a diagnostic at the position of a token
+func (l *linter) report(token Token, check string, format string, a ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Line:    token.Line,
+		Col:     token.Col,
+		Check:   check,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// Sorts the diagnostics by position
+func (l *linter) sort() {
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		a, b := l.diagnostics[i], l.diagnostics[j]
+		return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+	})
+}
+
+// Reports the extensions of the header without any instruction
+func (l *linter) unusedExtensions(header Token, used ExtensionCode) {
+	if used&ExtUdp == ExtUdp {
+		used |= ExtNet
+	}
+	col := header.Col + len("tl:")
+	for _, name := range strings.Split(header.Text[len("tl:"):], ":") {
+		if extCode, ok := SupportedExtensions[name]; ok && used&extCode == 0 {
+			l.report(Token{Line: header.Line, Col: col}, LintUnusedExtension,
+				"the %q extension is enabled but never used", name)
+		}
+		col += len(name) + 1
+	}
+}
+
+// Reports the instructions of disabled extensions in a comment
+// Only comments made of extension instructions are checked, others are likely text
+func (l *linter) disabledExtensions(comment Token, ext ExtensionCode) {
+	all := ^ExtensionCode(0)
+	for i := 0; i < len(comment.Text); i++ {
+		b := comment.Text[i]
+		if b != ' ' && b != '\t' && instructionExtension(b, all) == 0 {
+			return
+		}
+	}
+	for i := 0; i < len(comment.Text); i++ {
+		b := comment.Text[i]
+		if extCode := instructionExtension(b, all); extCode != 0 && !IsValidInstruction(b, ext) {
+			l.report(Token{Line: comment.Line, Col: comment.Col + i}, LintDisabledExtension,
+				"%q is an instruction of the %q extension, which is disabled", b, extensionName(extCode))
+		}
+	}
+}
+
+// Returns the header code of a single extension
+func extensionName(extCode ExtensionCode) string {
+	if extCode == ExtUdp {
+		return "udp"
+	}
+	for name, code := range SupportedExtensions {
+		if code == extCode {
+			return name
+		}
+	}
+	return ""
+}
+
+// Reports the brackets without a match
+// Returns true if all the brackets are balanced
+func (l *linter) balancedBrackets(instructions []Token) bool {
+	open := []Token{}
+	balanced := true
+	for _, token := range instructions {
+		if token.Text == "[" {
+			open = append(open, token)
+		} else if token.Text == "]" {
+			if len(open) == 0 {
+				l.report(token, LintUnbalanced, "']' has no matching '['")
+				balanced = false
+			} else {
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	for _, token := range open {
+		l.report(token, LintUnbalanced, "'[' has no matching ']'")
+		balanced = false
+	}
+	return balanced
+}
+
+// Lints balanced instructions using their analysis
+func (l *linter) lint(instructions []Token, ext ExtensionCode) {
+	code := tokenInstructions(instructions)
+	a := Analyze(code)
+	for i, token := range instructions {
+		if !a.Reachable[i] {
+			continue
+		}
+		switch {
+		case token.Text == "<" && a.High[i] == 0:
+			l.report(token, LintOutOfBoundary, "the pointer moves out of the memory to cell -1")
+		case token.Text == ">" && a.Low[i] == MemSize-1:
+			l.report(token, LintOutOfBoundary, "the pointer moves out of the memory to cell %d", MemSize)
+		case token.Text == "[" && a.Cell[i] == 0:
+			l.report(token, LintDeadLoop, "the loop can't be entered, the cell is always 0")
+		// With threads, another thread can change the cell
+		case token.Text == "[" && ext&ExtThr == 0 && a.Balanced[i] && flatLoop(code[i+1:matchingBracket(code, i)]):
+			l.report(token, LintInfiniteLoop, "the loop never ends once entered, the cell never changes")
+		}
+	}
+}
+
+// Returns true if a loop body only moves the pointer and outputs
+func flatLoop(body []byte) bool {
+	for _, b := range body {
+		if b != '<' && b != '>' && b != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the instructions of the tokens
+func tokenInstructions(tokens []Token) []byte {
+	code := make([]byte, 0, len(tokens))
+	for _, token := range tokens {
+		code = append(code, token.Text[0])
+	}
+	return code
+}
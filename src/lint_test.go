@@ -0,0 +1,57 @@
+package interpreter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+* Tests
+**/
+
+func TestLint(t *testing.T) {
+	tests := map[string][]Diagnostic{
+		"+[-]":               {},
+		"]\n [[":             {{1, 1, LintUnbalanced, "']' has no matching '['"}, {2, 2, LintUnbalanced, "'[' has no matching ']'"}, {2, 3, LintUnbalanced, "'[' has no matching ']'"}},
+		"[-]>[-]":            {{1, 1, LintDeadLoop, "the loop can't be entered, the cell is always 0"}, {1, 5, LintDeadLoop, "the loop can't be entered, the cell is always 0"}},
+		"+[-][-]":            {{1, 5, LintDeadLoop, "the loop can't be entered, the cell is always 0"}},
+		",[><.]":             {{1, 2, LintInfiniteLoop, "the loop never ends once entered, the cell never changes"}},
+		",[>]":               {},
+		"tl:thr +Y[-]>[]":    {},
+		">+[<<>>-]":          {{1, 5, LintOutOfBoundary, "the pointer moves out of the memory to cell -1"}},
+		"+[-]<":              {{1, 5, LintOutOfBoundary, "the pointer moves out of the memory to cell -1"}},
+		",[>]<<<<":           {},
+		"+. @ + hi":          {{1, 4, LintDisabledExtension, "'@' is an instruction of the \"net\" extension, which is disabled"}},
+		"tl:udp:thr:rnd\n&@": {{1, 8, LintUnusedExtension, "the \"thr\" extension is enabled but never used"}},
+		"tl:udp `":           {},
+	}
+	for src, expected := range tests {
+		diagnostics := Lint([]byte(src))
+		if len(diagnostics) != len(expected) {
+			t.Errorf("Expected %d problems in %q, got %+v", len(expected), src, diagnostics)
+			continue
+		}
+		for i, diagnostic := range diagnostics {
+			if diagnostic != expected[i] {
+				t.Errorf("Expected %+v in %q, got %+v", expected[i], src, diagnostic)
+			}
+		}
+	}
+}
+
+func TestLintSamples(t *testing.T) {
+	samples, err := filepath.Glob("../samples/*.bf")
+	if err != nil || len(samples) == 0 {
+		t.Fatalf("Failed to find the samples: %v", err)
+	}
+	for _, sample := range samples {
+		src, err := os.ReadFile(sample)
+		if err != nil {
+			t.Fatalf("Failed to read sample: %v", err)
+		}
+		if diagnostics := Lint(src); len(diagnostics) != 0 {
+			t.Errorf("Unexpected problems in %s: %+v", sample, diagnostics)
+		}
+	}
+}
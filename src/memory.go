@@ -9,17 +9,21 @@ var (
 const (
 	// Brainfuck has 30000 memory cells, this superset uses 2^16
 	MemSize = 65536
+	// Cells are allocated in chunks of this size when the memory is sized (see NewSizedMemory)
+	MemChunk = 1024
 )
 
 // The program working memory
 type Memory struct {
-	mem [65536]byte // Memory
-	p   int         // Memory pointer
+	mem []byte // Memory (possibly shared between threads), it grows up to MemSize
+	p   int    // Memory pointer
 }
 
 // Blanks out the working memory and resets the pointer
 func (m *Memory) Reset() {
-	m.mem = [MemSize]byte{}
+	for i := range m.mem {
+		m.mem[i] = 0
+	}
 	m.p = 0
 }
 
@@ -43,16 +47,51 @@ func (m *Memory) Set(b byte) {
 	m.mem[m.p] = b
 }
 
+// Sets bs to the bytes starting from the current one, without moving the pointer
+// Returns an error if bs doesn't fit in the memory
+func (m *Memory) SetBytes(bs []byte) error {
+	if m.p+len(bs) > len(m.mem) {
+		m.grow()
+	}
+	if m.p+len(bs) > len(m.mem) {
+		return ErrMemOutOfBoundary
+	}
+	copy(m.mem[m.p:], bs)
+	return nil
+}
+
+// Returns the position of the pointer
+func (m *Memory) Pointer() int {
+	return m.p
+}
+
+// Returns the byte at index i, 0 if i is out of boundary
+func (m *Memory) Peek(i int) byte {
+	if i < 0 || i >= len(m.mem) {
+		return 0
+	}
+	return m.mem[i]
+}
+
 // Moves the pointer to the next value if possible
 // Returns an error
 func (m *Memory) Next() error {
-	if m.p == MemSize-1 {
+	if m.p >= len(m.mem)-1 {
+		m.grow()
+	}
+	if m.p >= len(m.mem)-1 {
 		return ErrMemOutOfBoundary
 	}
 	m.p++
 	return nil
 }
 
+// Moves the pointer to the next value without checking the boundary
+// Only for moves proven to stay in the memory (see Analysis.SafeMoves)
+func (m *Memory) next() {
+	m.p++
+}
+
 // Moves the pointer to the previous value if possible
 // Returns an error
 func (m *Memory) Prev() error {
@@ -63,11 +102,26 @@ func (m *Memory) Prev() error {
 	return nil
 }
 
+// Moves the pointer to the previous value without checking the boundary
+// Only for moves proven to stay in the memory (see Analysis.SafeMoves)
+func (m *Memory) prev() {
+	m.p--
+}
+
+// Extends the cells to MemSize, the memory must not be shared with other threads
+func (m *Memory) grow() {
+	if len(m.mem) < MemSize {
+		mem := make([]byte, MemSize)
+		copy(mem, m.mem)
+		m.mem = mem
+	}
+}
+
 // Returns a copy of the memory from 0 to the last non-zero index
 func (m *Memory) Bytes() []byte {
 	// Find the last non-zero value
 	lastNonZero := 0
-	for i := MemSize - 1; i >= 0; i-- {
+	for i := len(m.mem) - 1; i >= 0; i-- {
 		if i == 0 && m.mem[i] == 0 {
 			// Special case
 			return []byte{}
@@ -85,10 +139,34 @@ func (m *Memory) Bytes() []byte {
 	return memBytes
 }
 
+// Returns a new memory with its own pointer sharing the same cells
+func (m *Memory) fork() *Memory {
+	return &Memory{
+		mem: m.mem,
+		p:   m.p,
+	}
+}
+
 // Returns a blank memory
 func NewMemory() *Memory {
 	return &Memory{
-		mem: [MemSize]byte{},
+		mem: make([]byte, MemSize),
+		p:   0,
+	}
+}
+
+// Returns a blank memory with at least size cells allocated, rounded up to MemChunk
+// The memory grows to MemSize when the pointer moves past the allocated cells
+func NewSizedMemory(size int) *Memory {
+	size = (size + MemChunk - 1) / MemChunk * MemChunk
+	if size < MemChunk {
+		size = MemChunk
+	}
+	if size > MemSize {
+		size = MemSize
+	}
+	return &Memory{
+		mem: make([]byte, size),
 		p:   0,
 	}
 }
@@ -72,6 +72,24 @@ func TestMemory(t *testing.T) {
 	if !bytes.Equal(bts, []byte{0, 1, 2}) {
 		t.Fatalf("Got %+d instead of [0, 1, 2]", bts)
 	}
+
+	// Set multiple bytes
+	if m.SetBytes([]byte{4, 5}) != nil {
+		t.Fatal("Failed to set bytes")
+	}
+	bts = m.Bytes()
+	if !bytes.Equal(bts, []byte{0, 4, 5}) || m.p != 1 {
+		t.Fatalf("Got %+d at %d instead of [0, 4, 5] at 1", bts, m.p)
+	}
+	m.p = MemSize - 1
+	if m.SetBytes([]byte{1, 1}) == nil {
+		t.Fatal("Didn't stop at boundary while setting bytes")
+	}
+
+	// Peek
+	if m.Pointer() != MemSize-1 || m.Peek(2) != 5 || m.Peek(-1) != 0 || m.Peek(MemSize) != 0 {
+		t.Fatalf("Unexpected pointer %d or peeked bytes", m.Pointer())
+	}
 }
 
 /*
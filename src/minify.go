@@ -0,0 +1,232 @@
+package interpreter
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Header codes in the order they are written by Minify
+var minifyHeaderOrder = []string{"udp", "net", "thr", "fio", "tim", "rnd", "ext"}
+
+// The state known while minifying, assuming the program starts with a blank memory
+type minifier struct {
+	out []byte
+	// Position of the pointer, -1 if unknown
+	pos int
+	// The current cell is known to be zero
+	zero bool
+	// No cell has been changed yet
+	fresh bool
+}
+
+// Returns the smallest source code running like the instructions of src:
+// comments are stripped, adjacent opposite instructions are cancelled, loops
+// that can't be entered are removed and the header is only kept for the
+// extensions that are used. Programs with unbalanced brackets or using the
+// threads extension only have their comments stripped.
+func Minify(src []byte) []byte {
+	// Reading from memory can't fail
+	inst, _ := NewInstructions(bytes.NewReader(src))
+	code := inst.instruction
+	if inst.extensions&ExtThr == 0 && balanced(code) {
+		for {
+			m := minifier{pos: 0, zero: true, fresh: true}
+			m.minify(code)
+			if bytes.Equal(m.out, code) {
+				break
+			}
+			code = m.out
+		}
+	}
+	return append(minifyHeader(code, inst.extensions), code...)
+}
+
+// Returns true if every bracket of code has a match
+func balanced(code []byte) bool {
+	depth := 0
+	for _, b := range code {
+		if b == '[' {
+			depth++
+		} else if b == ']' {
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+// Returns the smallest header enabling the extensions used by code
+func minifyHeader(code []byte, ext ExtensionCode) []byte {
+	var used ExtensionCode
+	for _, b := range code {
+		used |= instructionExtension(b, ext)
+	}
+	if used&(ExtNet|ExtUdp) != 0 && ext&ExtUdp == ExtUdp {
+		// The network instructions behave differently in datagram mode
+		used |= ExtNet | ExtUdp
+	}
+	codes := []string{}
+	for _, name := range minifyHeaderOrder {
+		extCode := SupportedExtensions[name]
+		if used&extCode == extCode {
+			codes = append(codes, name)
+			used &^= extCode
+		}
+	}
+	if len(codes) == 0 {
+		return []byte{}
+	}
+	return []byte("tl:" + strings.Join(codes, ":"))
+}
+
+// Returns the extension of an instruction enabled by ext, 0 for the base instructions
+func instructionExtension(b byte, ext ExtensionCode) ExtensionCode {
+	if IsValidInstruction(b, 0) {
+		return 0
+	}
+	for extCode := ExtNet; extCode <= ExtUdp; extCode <<= 1 {
+		if ext&extCode == extCode && IsValidInstruction(b, extCode) {
+			return extCode
+		}
+	}
+	return 0
+}
+
+// Minifies balanced code, one run of instructions at the time
+func (m *minifier) minify(code []byte) {
+	for i := 0; i < len(code); {
+		switch code[i] {
+		case '+', '-':
+			delta := 0
+			for ; i < len(code) && (code[i] == '+' || code[i] == '-'); i++ {
+				delta += int(44 - code[i]) // '+' is 43, '-' is 45
+			}
+			m.cell(delta)
+		case '<', '>':
+			low, high, delta := 0, 0, 0
+			for ; i < len(code) && (code[i] == '<' || code[i] == '>'); i++ {
+				delta += int(code[i]) - 61 // '<' is 60, '>' is 62
+				if delta < low {
+					low = delta
+				}
+				if delta > high {
+					high = delta
+				}
+			}
+			m.move(low, high, delta)
+		case '[':
+			end := matchingBracket(code, i)
+			m.loop(code[i+1 : end])
+			i = end + 1
+		default:
+			m.out = append(m.out, code[i])
+			if code[i] != '.' {
+				// Reads and extensions may set any cell
+				m.zero = false
+				m.fresh = false
+			}
+			i++
+		}
+	}
+}
+
+// Adds the shortest instructions changing the current cell by delta
+func (m *minifier) cell(delta int) {
+	delta = ((delta % 256) + 256) % 256
+	if delta == 0 {
+		return
+	}
+	if delta <= 128 {
+		m.out = append(m.out, bytes.Repeat([]byte{'+'}, delta)...)
+	} else {
+		m.out = append(m.out, bytes.Repeat([]byte{'-'}, 256-delta)...)
+	}
+	m.zero = false
+	m.fresh = false
+}
+
+// Adds the shortest moves by delta reaching the same lowest and highest cells
+func (m *minifier) move(low, high, delta int) {
+	moves := func(n int) {
+		if n > 0 {
+			m.out = append(m.out, bytes.Repeat([]byte{'>'}, n)...)
+		} else {
+			m.out = append(m.out, bytes.Repeat([]byte{'<'}, -n)...)
+		}
+	}
+	inBounds := m.pos >= 0 && m.pos+low >= 0 && m.pos+high < MemSize
+	switch {
+	case inBounds || (low == 0 && high == delta) || (high == 0 && low == delta):
+		moves(delta)
+	case delta > 0:
+		moves(low)
+		moves(high - low)
+		moves(delta - high)
+	default:
+		moves(high)
+		moves(low - high)
+		moves(delta - low)
+	}
+	if m.pos >= 0 {
+		m.pos += delta
+	}
+	if delta != 0 {
+		m.zero = m.fresh
+	}
+}
+
+// Adds a loop unless it can't be entered
+func (m *minifier) loop(body []byte) {
+	if m.zero {
+		return
+	}
+	pos := -1
+	if loopBalanced(body) {
+		pos = m.pos
+	}
+	m.out = append(m.out, '[')
+	inner := minifier{out: m.out, pos: pos}
+	inner.minify(body)
+	m.out = append(inner.out, ']')
+	m.pos = pos
+	m.zero = true
+	m.fresh = false
+}
+
+// Returns true if the pointer is back where it started after every iteration of a loop body
+func loopBalanced(body []byte) bool {
+	delta := 0
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '>':
+			delta++
+		case '<':
+			delta--
+		case '[':
+			end := matchingBracket(body, i)
+			if !loopBalanced(body[i+1 : end]) {
+				return false
+			}
+			i = end
+		}
+	}
+	return delta == 0
+}
+
+// Returns the index of the bracket closing the one at i in balanced code
+func matchingBracket(code []byte, i int) int {
+	depth := 0
+	for ; i < len(code); i++ {
+		if code[i] == '[' {
+			depth++
+		} else if code[i] == ']' {
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(code)
+}
@@ -0,0 +1,85 @@
+package interpreter
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Runs a program with the given input
+// Returns its output, its error and its memory
+func minifyRun(t *testing.T, src []byte, input string) (string, error, []byte) {
+	p, err := NewProgram(bytes.NewReader(src))
+	if err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	defer p.Close()
+	output := &bytes.Buffer{}
+	p.IOReader = strings.NewReader(input)
+	p.IOWriter = output
+	err = p.Run(10000000)
+	return output.String(), err, p.Memory.Bytes()
+}
+
+/*
+* Tests
+**/
+
+func TestMinify(t *testing.T) {
+	tests := map[string]string{
+		"":                         "",
+		"comment +-+ more":         "+",
+		"><<>":                     "><<>",
+		">><<>":                    ">",
+		",[>]<><>":                 ",[>]<>",
+		">><<":                     "",
+		"<>":                       "<>",
+		"+>-<":                     "+>-<",
+		"[-]>[-]+":                 ">+",
+		"+[-][+].":                 "+[-].",
+		"+[-]+-[>]":                "+[-]",
+		"+[<<>>-]":                 "+[<<>>-]",
+		">>+[<<>>-]":               ">>+[-]",
+		"+[<+>-]":                  "+[<+>-]",
+		",[>]<>":                   ",[>]<>",
+		strings.Repeat("+", 200):   strings.Repeat("-", 56),
+		"tl:net:rnd read @ only":   "tl:net@",
+		"tl:net:fio no extensions": "",
+		"tl:udp `":                 "tl:udp`",
+		"tl:udp @":                 "tl:udp@",
+		"tl:thr +-Y":               "tl:thr+-Y",
+		"+]+-":                     "+]+-",
+	}
+	for src, expected := range tests {
+		if minified := string(Minify([]byte(src))); minified != expected {
+			t.Errorf("Failed to minify %q: expected %q, got %q", src, expected, minified)
+		}
+	}
+}
+
+func TestMinifySamples(t *testing.T) {
+	samples := map[string]string{
+		"helloWorld.bf":         "",
+		"extendedHelloWorld.bf": "",
+		"printAscii.bf":         "",
+		"rot13.bf":              "Hello, World!\n",
+		"charToBf.bf":           "hi",
+	}
+	for name, input := range samples {
+		src, err := os.ReadFile("../samples/" + name)
+		if err != nil {
+			t.Fatalf("Failed to read sample: %v", err)
+		}
+		minified := Minify(src)
+		if len(minified) > len(src) {
+			t.Errorf("Minified %s is longer than the source", name)
+		}
+		output, err, memory := minifyRun(t, src, input)
+		minOutput, minErr, minMemory := minifyRun(t, minified, input)
+		if output != minOutput || err != minErr || !bytes.Equal(memory, minMemory) {
+			t.Errorf("Minified %s runs differently: %q, %v, %v instead of %q, %v, %v",
+				name, minOutput, minErr, minMemory, output, err, memory)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package interpreter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Executions and time spent by each instruction of a program
+type Profile struct {
+	// Executions by instruction index
+	Counts []int
+	// Time spent by instruction index, until the next instruction started
+	Times []time.Duration
+	// The last instruction run and when it started (-1 if none)
+	last     int
+	lastTime time.Time
+}
+
+// The executions of a loop
+type LoopProfile struct {
+	// Instruction indexes of the brackets
+	Start, End int
+	// Position of the opening bracket in the source code
+	Line, Col int
+	// Times the loop was reached and times its body ran
+	Entries, Iterations int
+	// Instructions executed and time spent in the loop, including the brackets
+	Steps int
+	Time  time.Duration
+}
+
+// The executions of an instruction
+type InstructionProfile struct {
+	Token Token
+	Count int
+	Time  time.Duration
+}
+
+// Returns an empty profile
+func NewProfile() *Profile {
+	return &Profile{last: -1}
+}
+
+// Profiles the program and its threads, by instruction index
+func WithProfile(profile *Profile) ProgramOption {
+	return func(p *Program) {
+		p.profile = profile
+	}
+}
+
+// Records the start of the instruction at index i
+func (pr *Profile) hit(i int) {
+	now := time.Now()
+	pr.Stop()
+	for len(pr.Counts) <= i {
+		pr.Counts = append(pr.Counts, 0)
+		pr.Times = append(pr.Times, 0)
+	}
+	pr.Counts[i]++
+	pr.last, pr.lastTime = i, now
+}
+
+// Attributes the time since the last instruction started to it
+func (pr *Profile) Stop() {
+	if pr.last >= 0 {
+		pr.Times[pr.last] += time.Since(pr.lastTime)
+		pr.last = -1
+	}
+}
+
+// Returns the instructions executed and the time spent
+func (pr *Profile) Total() (int, time.Duration) {
+	steps, total := 0, time.Duration(0)
+	for i, count := range pr.Counts {
+		steps += count
+		total += pr.Times[i]
+	}
+	return steps, total
+}
+
+// Returns the count and time of instruction i, 0 if it never ran
+func (pr *Profile) at(i int) (int, time.Duration) {
+	if i >= len(pr.Counts) {
+		return 0, 0
+	}
+	return pr.Counts[i], pr.Times[i]
+}
+
+// Returns the instruction tokens of the source code, in the order they run
+func InstructionTokens(src []byte) []Token {
+	_, tokens := Scan(src)
+	instructions := []Token{}
+	for _, token := range tokens {
+		if token.Kind == TokenInstruction {
+			instructions = append(instructions, token)
+		}
+	}
+	return instructions
+}
+
+// Returns the profile of the instructions that ran, the slowest first
+// instructions are the tokens of the profiled source code (see InstructionTokens)
+func (pr *Profile) Instructions(instructions []Token) []InstructionProfile {
+	profiles := []InstructionProfile{}
+	for i, token := range instructions {
+		if count, elapsed := pr.at(i); count > 0 {
+			profiles = append(profiles, InstructionProfile{Token: token, Count: count, Time: elapsed})
+		}
+	}
+	sort.SliceStable(profiles, func(i, j int) bool {
+		if profiles[i].Time != profiles[j].Time {
+			return profiles[i].Time > profiles[j].Time
+		}
+		return profiles[i].Count > profiles[j].Count
+	})
+	return profiles
+}
+
+// Returns the profile of the loops that ran, the slowest first
+// instructions are the tokens of the profiled source code (see InstructionTokens)
+func (pr *Profile) Loops(instructions []Token) []LoopProfile {
+	loops := []LoopProfile{}
+	open := []int{}
+	for i, token := range instructions {
+		if token.Text == "[" {
+			open = append(open, i)
+			continue
+		}
+		if token.Text != "]" || len(open) == 0 {
+			continue
+		}
+		start := open[len(open)-1]
+		open = open[:len(open)-1]
+		loop := LoopProfile{Start: start, End: i, Line: instructions[start].Line, Col: instructions[start].Col}
+		loop.Entries, _ = pr.at(start)
+		loop.Iterations, _ = pr.at(i)
+		if loop.Entries == 0 {
+			continue
+		}
+		for j := start; j <= i; j++ {
+			count, elapsed := pr.at(j)
+			loop.Steps += count
+			loop.Time += elapsed
+		}
+		loops = append(loops, loop)
+	}
+	sort.SliceStable(loops, func(i, j int) bool {
+		if loops[i].Time != loops[j].Time {
+			return loops[i].Time > loops[j].Time
+		}
+		return loops[i].Steps > loops[j].Steps
+	})
+	return loops
+}
+
+// Writes the profile as folded stacks for flame graph tools: a
+// "frame;frame;... weight" line for each instruction that ran, where the
+// frames are the enclosing loops (brackets included) and the weight is the executions (or the
+// time in nanoseconds if byTime)
+// Returns an error
+func (pr *Profile) WriteFolded(w io.Writer, instructions []Token, byTime bool) error {
+	stack := []string{}
+	for i, token := range instructions {
+		if token.Text == "[" {
+			stack = append(stack, fmt.Sprintf("loop %d:%d", token.Line, token.Col))
+		}
+		count, elapsed := pr.at(i)
+		weight := int64(count)
+		if byTime {
+			weight = elapsed.Nanoseconds()
+		}
+		if weight > 0 {
+			frames := strings.Join(append(stack, fmt.Sprintf("%s %d:%d", token.Text, token.Line, token.Col)), ";")
+			if _, err := fmt.Fprintf(w, "%s %d\n", frames, weight); err != nil {
+				return err
+			}
+		}
+		if token.Text == "]" && len(stack) > 0 {
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package interpreter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+/*
+* Tests
+**/
+
+func TestProfile(t *testing.T) {
+	src := "++\n[>+++<-]>."
+	profile := NewProfile()
+	p, err := NewProgram(strings.NewReader(src), WithProfile(profile))
+	if err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	p.IOWriter = &bytes.Buffer{}
+	if err := p.Run(1000); err != nil {
+		t.Fatalf("Failed to run program: %v", err)
+	}
+	profile.Stop()
+	expected := []int{1, 1, 1, 2, 2, 2, 2, 2, 2, 2, 1, 1}
+	if len(profile.Counts) != len(expected) {
+		t.Fatalf("Expected counts %v, got %v", expected, profile.Counts)
+	}
+	for i, count := range expected {
+		if profile.Counts[i] != count {
+			t.Fatalf("Expected counts %v, got %v", expected, profile.Counts)
+		}
+	}
+	if steps, _ := profile.Total(); steps != 19 {
+		t.Fatalf("Expected 19 steps, got %d", steps)
+	}
+	instructions := InstructionTokens([]byte(src))
+	loops := profile.Loops(instructions)
+	if len(loops) != 1 {
+		t.Fatalf("Expected 1 loop, got %v", loops)
+	}
+	loop := loops[0]
+	if loop.Start != 2 || loop.End != 9 || loop.Line != 2 || loop.Col != 1 ||
+		loop.Entries != 1 || loop.Iterations != 2 || loop.Steps != 15 {
+		t.Fatalf("Unexpected loop profile %+v", loop)
+	}
+	hot := profile.Instructions(instructions)
+	if len(hot) != len(instructions) {
+		t.Fatalf("Expected %d instruction profiles, got %d", len(instructions), len(hot))
+	}
+	var folded bytes.Buffer
+	if err := profile.WriteFolded(&folded, instructions, false); err != nil {
+		t.Fatalf("Failed to write the folded stacks: %v", err)
+	}
+	expectedFolded := "+ 1:1 1\n+ 1:2 1\nloop 2:1;[ 2:1 1\nloop 2:1;> 2:2 2\nloop 2:1;+ 2:3 2\n" +
+		"loop 2:1;+ 2:4 2\nloop 2:1;+ 2:5 2\nloop 2:1;< 2:6 2\nloop 2:1;- 2:7 2\nloop 2:1;] 2:8 2\n" +
+		"> 2:9 1\n. 2:10 1\n"
+	if folded.String() != expectedFolded {
+		t.Fatalf("Expected the folded stacks\n%s\ngot\n%s", expectedFolded, folded.String())
+	}
+}
+
+func TestProfileThreads(t *testing.T) {
+	src := "tl:thr\nY[-]+"
+	profile := NewProfile()
+	p, err := NewProgram(strings.NewReader(src), WithProfile(profile), WithThreadSeed(1))
+	if err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	if err := p.Run(1000); err != nil {
+		t.Fatalf("Failed to run program: %v", err)
+	}
+	profile.Stop()
+	// Both threads run the instructions after the fork
+	if len(profile.Counts) != 5 || profile.Counts[0] != 1 || profile.Counts[4] != 2 {
+		t.Fatalf("Unexpected counts %v", profile.Counts)
+	}
+}
+
+func TestProfileUnused(t *testing.T) {
+	profile := NewProfile()
+	profile.Stop()
+	instructions := InstructionTokens([]byte("+[-]"))
+	if steps, total := profile.Total(); steps != 0 || total != 0 {
+		t.Fatalf("Expected an empty profile, got %d steps in %s", steps, total)
+	}
+	if loops := profile.Loops(instructions); len(loops) != 0 {
+		t.Fatalf("Expected no loops, got %v", loops)
+	}
+	var folded bytes.Buffer
+	if err := profile.WriteFolded(&folded, instructions, true); err != nil || folded.Len() != 0 {
+		t.Fatalf("Expected no folded stacks, got %q (%v)", folded.String(), err)
+	}
+}
@@ -23,14 +23,31 @@ type Program struct {
 
 	// Network Extension
 	Network *Network
+	// Threads Extension
+	Threads *Threads
+	// Files Extension
+	Files *Files
+	// Clock for the Time Extension
+	Clock Clock
+	// Random Extension
+	Random *Random
 
 	// Writer for IO output
 	IOWriter io.Writer
 	// Reader for IO input
 	IOReader io.Reader
+
+	// The thread run by this program (nil for the main thread)
+	thread *thread
+	// Execution profile (nil if not profiling)
+	profile *Profile
 }
 
+// Configures a program on creation
+type ProgramOption func(*Program)
+
 // Runs the entire program until done, error, or reached execution limit
+// Returns an *ExitError if the program ended itself with an exit code
 func (p *Program) Run(limit int) error {
 	for i := 0; i < limit; i++ {
 		err := p.RunNext()
@@ -45,21 +62,42 @@ func (p *Program) Run(limit int) error {
 }
 
 // Runs the next instruction
+// With the threads extension enabled, runs the next instruction of the next scheduled thread
 // Returns an error if any
 func (p *Program) RunNext() error {
+	if p.thread == nil && p.Instructions.extensions&ExtThr == ExtThr {
+		return p.Threads.runNext(p)
+	}
+	return p.runInstruction()
+}
+
+// Runs the next instruction of this program
+// Returns an error if any
+func (p *Program) runInstruction() error {
 	instruction := p.Instructions.Pop()
 	if instruction == 0 {
 		return ErrProgramDone
 	}
+	if p.profile != nil {
+		p.profile.hit(p.Instructions.pc - 1)
+	}
 	/*
 	* Base
 	**/
 	// Increment the data pointer
 	if instruction == '>' {
+		if p.Instructions.safeMove() {
+			p.Memory.next()
+			return nil
+		}
 		return p.Memory.Next()
 	}
 	// Decrement the data pointer
 	if instruction == '<' {
+		if p.Instructions.safeMove() {
+			p.Memory.prev()
+			return nil
+		}
 		return p.Memory.Prev()
 	}
 	// Increment (by one) the byte at the data pointer
@@ -122,7 +160,19 @@ func (p *Program) RunNext() error {
 	// On error sets the byte at the data pointer to `0`
 	if instruction == '?' && extNet {
 		p.Memory.Set(p.Network.Receive())
-		return nil
+		return p.Network.Err()
+	}
+	// Receives a byte without waiting and writes it to the byte at the data pointer
+	// Sets the next byte to `1` if a byte was received, `0` otherwise
+	if instruction == '|' && extNet {
+		b, ok := p.Network.Poll()
+		if err := p.Network.Err(); err != nil {
+			return err
+		}
+		if ok {
+			return p.Memory.SetBytes([]byte{b, 1})
+		}
+		return p.Memory.SetBytes([]byte{0, 0})
 	}
 	// Queues the byte at the data pointer to be sent
 	if instruction == '^' && extNet {
@@ -135,16 +185,174 @@ func (p *Program) RunNext() error {
 		if ok := p.Network.Push(); ok {
 			p.Memory.Set(0)
 		}
+		return p.Network.Err()
+	}
+	// Selects the connection with the handle of the byte at the data pointer
+	// Sets the data pointer value to `0` if successful, `1` otherwise
+	if instruction == '=' && extNet {
+		if ok := p.Network.SelectConn(p.Memory.Get()); ok {
+			p.Memory.Set(0)
+		} else {
+			p.Memory.Set(1)
+		}
 		return nil
 	}
+	// Closes the selected connection, or the listener if no connection is selected
+	if instruction == '\\' && extNet {
+		p.Network.CloseConn()
+		return nil
+	}
+	// Writes the network state, the bytes available and the last error code starting at the data pointer
+	if instruction == '/' && extNet {
+		status := p.Network.Status()
+		if err := p.Network.Err(); err != nil {
+			return err
+		}
+		return p.Memory.SetBytes(status)
+	}
+	// Writes the source address of the last datagram starting at the data pointer
+	if instruction == '`' && p.Instructions.extensions&ExtUdp == ExtUdp {
+		return p.Memory.SetBytes(p.Network.Source())
+	}
+	/*
+	* Extension: Threads
+	**/
+	extThr := p.Instructions.extensions&ExtThr == ExtThr
+	// Forks a new thread sharing the memory, the current byte is set to `0`
+	// The new thread starts from the next byte which is set to `1`
+	if instruction == 'Y' && extThr {
+		return p.Threads.fork(p)
+	}
+	// Waits for all the threads forked by this thread to terminate
+	if instruction == 'W' && extThr {
+		if p.Threads.isWaiting(p) {
+			// Run this instruction again next time
+			p.Instructions.pc--
+		}
+		return nil
+	}
+	/*
+	* Extension: Files
+	**/
+	extFio := p.Instructions.extensions&ExtFio == ExtFio
+	// Opens and selects the file at the allowlist index of the byte at the data pointer
+	// Sets the data pointer value to `0` if successful, `1` otherwise
+	if instruction == '$' && extFio {
+		if ok := p.Files.Select(p.Memory.Get()); ok {
+			p.Memory.Set(0)
+		} else {
+			p.Memory.Set(1)
+		}
+		return nil
+	}
+	// Reads one byte from the selected file, stores it at the data pointer (`0` at the end of the file)
+	// Sets the next byte to `0` if successful, `2` at the end of the file, `1` otherwise
+	if instruction == '(' && extFio {
+		b, err := p.Files.Read()
+		if err == nil {
+			return p.Memory.SetBytes([]byte{b, 0})
+		}
+		if err == io.EOF {
+			return p.Memory.SetBytes([]byte{0, 2})
+		}
+		return p.Memory.SetBytes([]byte{0, 1})
+	}
+	// Writes the byte at the data pointer to the selected file
+	// Sets the data pointer value to `0` if successful, `1` otherwise
+	if instruction == ')' && extFio {
+		if err := p.Files.Write(p.Memory.Get()); err == nil {
+			p.Memory.Set(0)
+		} else {
+			p.Memory.Set(1)
+		}
+		return nil
+	}
+	/*
+	* Extension: Time
+	**/
+	extTim := p.Instructions.extensions&ExtTim == ExtTim
+	// Sleeps for the byte at the data pointer times 10 milliseconds
+	if instruction == '%' && extTim {
+		sleepTicks(p.Clock, p.Memory.Get())
+		return nil
+	}
+	// Writes the time elapsed in 10 milliseconds ticks in the 4 bytes
+	// starting at the data pointer (big-endian)
+	if instruction == '\'' && extTim {
+		return p.Memory.SetBytes(elapsedTicks(p.Clock))
+	}
+	/*
+	* Extension: Random
+	**/
+	extRnd := p.Instructions.extensions&ExtRnd == ExtRnd
+	// Sets the byte at the data pointer to a pseudo-random value
+	if instruction == '&' && extRnd {
+		p.Memory.Set(p.Random.Byte())
+		return nil
+	}
+	// Seeds the generator with the byte at the data pointer
+	if instruction == '"' && extRnd {
+		p.Random.SetSeed(int64(p.Memory.Get()))
+		return nil
+	}
+	/*
+	* Extension: Exit
+	**/
+	// Terminates the program with the byte at the data pointer as exit code
+	if instruction == '!' && p.Instructions.extensions&ExtExt == ExtExt {
+		return p.exit(p.Memory.Get())
+	}
 
 	return ErrProgramUnknown
 }
 
-// Rests memory and the program counter
+// Rests memory, the program counter, the threads and closes the files
 func (p *Program) Reset() {
+	p.setDefaults()
 	p.Instructions.Reset()
 	p.Memory.Reset()
+	p.Threads.Reset()
+	p.Files.Close()
+}
+
+// Releases the resources held by the program, the network can't be used anymore
+// Returns the first error encountered, if any
+func (p *Program) Close() error {
+	var err error
+	if p.Files != nil {
+		err = p.Files.Close()
+	}
+	if p.Network != nil {
+		if netErr := p.Network.Close(); err == nil {
+			err = netErr
+		}
+	}
+	return err
+}
+
+// Creates the missing parts of a program that wasn't created by NewProgram
+func (p *Program) setDefaults() {
+	if p.Instructions == nil {
+		p.Instructions = &Instructions{}
+	}
+	if p.Memory == nil {
+		p.Memory = NewMemory()
+	}
+	if p.Network == nil {
+		p.Network = NewNetwork()
+	}
+	if p.Threads == nil {
+		p.Threads = NewThreads()
+	}
+	if p.Files == nil {
+		p.Files = NewFiles()
+	}
+	if p.Clock == nil {
+		p.Clock = NewSystemClock()
+	}
+	if p.Random == nil {
+		p.Random = NewRandom()
+	}
 }
 
 // Loads a new program (without resetting memory)
@@ -154,10 +362,38 @@ func (p *Program) LoadProgram(r io.Reader) error {
 		return err
 	}
 
+	p.setDefaults()
 	p.Instructions = inst
+	p.Threads.Reset()
+	p.Memory.grow()
+	p.Network.SetDatagram(p.HasExtensions(ExtUdp))
 	return nil
 }
 
+// Loads a new program (without resetting memory) keeping the enabled extensions
+// Extensions in the header of the new program are enabled too
+func (p *Program) LoadSnippet(r io.Reader) error {
+	p.setDefaults()
+	inst, err := newInstructions(r, p.Instructions.extensions)
+	if err != nil {
+		return err
+	}
+
+	p.Instructions = inst
+	p.Threads.Reset()
+	p.Memory.grow()
+	p.Network.SetDatagram(p.HasExtensions(ExtUdp))
+	return nil
+}
+
+// Enables the given extensions for the next loaded snippet (see LoadSnippet)
+func (p *Program) EnableExtensions(ec ExtensionCode) {
+	p.setDefaults()
+	p.Instructions.extensions |= ec
+	p.Memory.grow()
+	p.Network.SetDatagram(p.HasExtensions(ExtUdp))
+}
+
 // Returns true if the given extension is enabled
 func (p Program) HasExtensions(ec ExtensionCode) bool {
 	return p.Instructions.extensions&ec == ec
@@ -169,17 +405,33 @@ func (p Program) GetInstructions() []byte {
 }
 
 // Returns a new empty program
-func NewProgram(r io.Reader) (Program, error) {
+func NewProgram(r io.Reader, opts ...ProgramOption) (Program, error) {
 	inst, err := NewInstructions(r)
 	if err != nil {
 		return Program{}, err
 	}
+	// The program starts with a blank memory, its analysis holds until another is loaded
+	analysis := Analyze(inst.instruction)
+	inst.safe = analysis.SafeMoves(inst.instruction)
+	memory := NewMemory()
+	if analysis.Bounded() && inst.extensions&ExtThr == 0 {
+		memory = NewSizedMemory(analysis.MaxCell + 1)
+	}
 
-	return Program{
+	p := Program{
 		Instructions: inst,
-		Memory:       NewMemory(),
+		Memory:       memory,
 		Network:      NewNetwork(),
+		Threads:      NewThreads(),
+		Files:        NewFiles(),
+		Clock:        NewSystemClock(),
+		Random:       NewRandom(),
 		IOWriter:     os.Stdout,
 		IOReader:     os.Stdin,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(&p)
+	}
+	p.Network.SetDatagram(p.HasExtensions(ExtUdp))
+	return p, nil
 }
@@ -42,6 +42,57 @@ func TestProgram(t *testing.T) {
 	}
 }
 
+func TestLoadSnippet(t *testing.T) {
+	p, err := NewProgram(strings.NewReader("+++"))
+	if err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	if err := p.Run(100); err != nil {
+		t.Fatalf("Failed to run program: %v", err)
+	}
+	// Snippets keep the memory and the enabled extensions
+	p.EnableExtensions(ExtRnd)
+	p.LoadSnippet(strings.NewReader(">+!&"))
+	if string(p.GetInstructions()) != ">+&" {
+		t.Fatalf("Expected the random extension to be enabled, instead got %s", p.GetInstructions())
+	}
+	p.LoadSnippet(strings.NewReader("tl:ext +!"))
+	if string(p.GetInstructions()) != "+!" || !p.HasExtensions(ExtRnd|ExtExt) {
+		t.Fatalf("Expected the header extensions to be added, instead got %s", p.GetInstructions())
+	}
+	p.LoadSnippet(strings.NewReader(">++"))
+	if err := p.Run(100); err != nil {
+		t.Fatalf("Failed to run snippet: %v", err)
+	}
+	if !bytes.Equal(p.Memory.Bytes(), []byte{3, 2}) {
+		t.Fatalf("Expected the memory to persist, instead got %+d", p.Memory.Bytes())
+	}
+}
+
+func TestProgramLiteral(t *testing.T) {
+	// A program built from its fields creates the missing extensions when needed
+	p := Program{Memory: NewMemory(), IOWriter: &strings.Builder{}}
+	p.Reset()
+	if err := p.LoadProgram(strings.NewReader("tl:thr:udp +Y.")); err != nil {
+		t.Fatalf("Failed to load program: %v", err)
+	}
+	if err := p.Run(100); err != nil {
+		t.Fatalf("Failed to run program: %v", err)
+	}
+	p = Program{}
+	p.EnableExtensions(ExtRnd)
+	if err := p.LoadSnippet(strings.NewReader("+>++")); err != nil {
+		t.Fatalf("Failed to load snippet: %v", err)
+	}
+	if err := p.Run(100); err != nil || !bytes.Equal(p.Memory.Bytes(), []byte{1, 2}) {
+		t.Fatalf("Expected to run the snippet, instead got %+d (%v)", p.Memory.Bytes(), err)
+	}
+	if err := (&Program{}).Close(); err != nil {
+		t.Fatalf("Failed to close an empty program: %v", err)
+	}
+	p.Close()
+}
+
 func TestRunNext(t *testing.T) {
 	t.Run("MemoryOps", func(t *testing.T) {
 		p, err := NewProgram(strings.NewReader(`+ > +++ > +++ < -`))
@@ -108,7 +159,8 @@ func TestRunNext(t *testing.T) {
 		}
 	})
 	t.Run("Network", func(t *testing.T) {
-		p, err := NewProgram(strings.NewReader(`tl:net ++*-@^?`))
+		p, err := NewProgram(strings.NewReader(`tl:net ++*-@^?`),
+			WithNetwork(NewNetwork(WithTransport(NewMemoryTransport()))))
 		if err != nil {
 			t.Fatalf("Failed to load test program: %v", err)
 		}
@@ -0,0 +1,64 @@
+package interpreter
+
+import "strings"
+
+// The kind of a source token
+type TokenKind uint8
+
+const (
+	// The extensions header (see ParseHeader)
+	TokenHeader TokenKind = 0
+	// A single instruction
+	TokenInstruction TokenKind = 1
+	// Text that is not an instruction, trimmed, it never spans multiple lines
+	TokenComment TokenKind = 2
+)
+
+// A piece of source code and its position
+type Token struct {
+	Kind TokenKind
+	Text string
+	// Line and column (in bytes) of the first character, starting from 1
+	Line int
+	Col  int
+}
+
+// Splits the source code into tokens, keeping the comments
+// Returns the extensions enabled by the header and the tokens
+func Scan(src []byte) (ExtensionCode, []Token) {
+	ext, headerLen := ParseHeader(src)
+	tokens := []Token{}
+	if headerLen > 0 {
+		tokens = append(tokens, Token{Kind: TokenHeader, Text: string(src[:headerLen]), Line: 1, Col: 1})
+	}
+	line, col := 1, headerLen+1
+	// Start of the pending comment, -1 if none
+	commentStart, commentLine, commentCol := -1, 0, 0
+	endComment := func(end int) {
+		if commentStart < 0 {
+			return
+		}
+		text := strings.TrimRight(string(src[commentStart:end]), " \t\r")
+		tokens = append(tokens, Token{Kind: TokenComment, Text: text, Line: commentLine, Col: commentCol})
+		commentStart = -1
+	}
+	for i := headerLen; i < len(src); i++ {
+		b := src[i]
+		switch {
+		case IsValidInstruction(b, ext):
+			endComment(i)
+			tokens = append(tokens, Token{Kind: TokenInstruction, Text: string(b), Line: line, Col: col})
+		case b == '\n':
+			endComment(i)
+			line++
+			col = 0
+		case b == ' ' || b == '\t' || b == '\r':
+			// Whitespace is only kept inside comments
+		case commentStart < 0:
+			commentStart, commentLine, commentCol = i, line, col
+		}
+		col++
+	}
+	endComment(len(src))
+	return ext, tokens
+}
@@ -0,0 +1,40 @@
+package interpreter
+
+import "testing"
+
+/*
+* Tests
+**/
+
+func TestScan(t *testing.T) {
+	ext, tokens := Scan([]byte("tl:net:thr count\n+@ listen  \n\n [-]"))
+	if ext != ExtNet|ExtThr {
+		t.Fatalf("Failed to scan the header extensions: %d", ext)
+	}
+	expected := []Token{
+		{TokenHeader, "tl:net:thr", 1, 1},
+		{TokenComment, "count", 1, 12},
+		{TokenInstruction, "+", 2, 1},
+		{TokenInstruction, "@", 2, 2},
+		{TokenComment, "listen", 2, 4},
+		{TokenInstruction, "[", 4, 2},
+		{TokenInstruction, "-", 4, 3},
+		{TokenInstruction, "]", 4, 4},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d: %+v", len(expected), len(tokens), tokens)
+	}
+	for i, token := range tokens {
+		if token != expected[i] {
+			t.Errorf("Expected token %d to be %+v, got %+v", i, expected[i], token)
+		}
+	}
+	// Without a header the extension instructions are comments
+	ext, tokens = Scan([]byte("@+"))
+	if ext != 0 || len(tokens) != 2 || tokens[0].Kind != TokenComment || tokens[1] != (Token{TokenInstruction, "+", 1, 2}) {
+		t.Fatalf("Failed to scan source without a header: %d %+v", ext, tokens)
+	}
+	if _, tokens = Scan([]byte{}); len(tokens) != 0 {
+		t.Fatalf("Expected no tokens for empty source, got %+v", tokens)
+	}
+}